@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/request"
+	"github.com/tslnc04/tax-calculator/internal/response"
+	"golang.org/x/time/rate"
+)
+
+// batchResultHeader is the header of the columns added to each row of the input in the output of a batch.
+var batchResultHeader = []string{
+	"net", "gross", "total_taxes", "federal_taxes", "state_taxes", "local_taxes", "territory_taxes", "error",
+}
+
+// runBatch calculates every row of the CSV input and writes the results as CSV to stdout. If the input is -, it is read
+// from stdin. It returns the exit code for the program, which is 1 if any row failed.
+func runBatch(input string) int {
+	if flag.NArg() != 0 || isFlagSet("target-net") {
+		glog.Error("Salary and -target-net must not be specified with -input")
+
+		return 2
+	}
+
+	reader := io.Reader(os.Stdin)
+
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			glog.Errorf("Failed to open input: %s", err)
+
+			return 2
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	failed, err := calculateBatch(context.Background(), reader, os.Stdout,
+		rate.NewLimiter(rate.Every(rateLimit), 1))
+	if err != nil {
+		glog.Errorf("Failed to calculate batch: %s", err)
+
+		return 2
+	}
+
+	if failed > 0 {
+		glog.Errorf("Failed to calculate %d rows", failed)
+
+		return 1
+	}
+
+	return 0
+}
+
+// calculateBatch reads CSV rows from the reader and writes each row followed by its results to the writer. The header
+// row names the salary column and any flags for the options of the row, which are added to the options from the command
+// line. Empty cells are ignored. Requests are rate limited by the limiter. A row that fails has its error written in
+// the error column, and the number of failed rows is returned. An error is only returned if the input could not be read
+// or the output could not be written.
+func calculateBatch(ctx context.Context, reader io.Reader, writer io.Writer, limiter request.Limiter) (int, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	csvWriter := csv.NewWriter(writer)
+
+	err = csvWriter.Write(append(header[:len(header):len(header)], batchResultHeader...))
+	if err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	failed := 0
+
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return failed, fmt.Errorf("failed to read row %d: %w", row, err)
+		}
+
+		glog.V(10).Infof("Calculating row %d: %v", row, record)
+
+		response, err := calculateRow(ctx, header, record, limiter)
+		if err != nil {
+			glog.V(10).Infof("Failed to calculate row %d: %s", row, err)
+
+			failed++
+		}
+
+		err = csvWriter.Write(append(record, batchResult(response, err)...))
+		if err != nil {
+			return failed, fmt.Errorf("failed to write row %d: %w", row, err)
+		}
+
+		// Flush after each row so results are visible as they are calculated.
+		csvWriter.Flush()
+
+		if err = csvWriter.Error(); err != nil {
+			return failed, fmt.Errorf("failed to write row %d: %w", row, err)
+		}
+	}
+
+	return failed, nil
+}
+
+// calculateRow sends the request for a row of the batch, waiting on the limiter before every attempt. The options of
+// the row are the options from the command line with the flags from the row applied.
+func calculateRow(ctx context.Context, header, record []string, limiter request.Limiter) (*response.Response, error) {
+	rowOptions := globalOptions.clone()
+	flags := flag.NewFlagSet("row", flag.ContinueOnError)
+	rowOptions.register(flags)
+
+	salary := -1.0
+
+	for column, value := range record {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if header[column] == "salary" {
+			var err error
+
+			salary, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("salary is not a valid float: %w", err)
+			}
+
+			continue
+		}
+
+		err := flags.Set(header[column], value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", header[column], err)
+		}
+	}
+
+	if salary < 0 && rowOptions.hourlyRate == 0 {
+		return nil, errors.New("salary or hourly-rate must be specified")
+	}
+
+	err := rowOptions.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := rowOptions.buildRequest(ctx)
+	if salary >= 0 {
+		rowOptions.withSalary(builder, salary)
+	}
+
+	return builder.WithLimiter(limiter).SendContext(ctx)
+}
+
+// batchResult returns the result columns for a row from its response or error.
+func batchResult(resp *response.Response, err error) []string {
+	if err != nil {
+		return []string{"", "", "", "", "", "", "", err.Error()}
+	}
+
+	amounts := []float64{
+		resp.Net.Amount,
+		resp.Gross.Amount,
+		resp.Taxes.SummaryEntity.Amount,
+		resp.Taxes.Federal.SummaryEntity.Amount,
+		resp.Taxes.State.SummaryEntity.Amount,
+		resp.Taxes.Local.SummaryEntity.Amount,
+		resp.Taxes.Territory.SummaryEntity.Amount,
+	}
+
+	result := make([]string, 0, len(batchResultHeader))
+	for _, amount := range amounts {
+		result = append(result, strconv.FormatFloat(amount, 'f', 2, 64))
+	}
+
+	return append(result, "")
+}
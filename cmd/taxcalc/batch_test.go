@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+)
+
+// useFakeADP points the options from the command line at a fake ADP server until the end of the test.
+func useFakeADP(t *testing.T) *fakeadp.Server {
+	t.Helper()
+
+	server := fakeadp.NewServer()
+	t.Cleanup(server.Close)
+
+	previous := globalOptions
+	globalOptions = options{
+		apiURL:   server.APIURL(),
+		registry: jurisdiction.NewRegistry(&jurisdiction.Loader{BaseURL: server.URL}),
+	}
+
+	t.Cleanup(func() { globalOptions = previous })
+
+	return server
+}
+
+// countingLimiter is a [request.Limiter] that never waits and counts how many times it was waited on.
+type countingLimiter struct {
+	waits int
+}
+
+func (limiter *countingLimiter) Wait(context.Context) error {
+	limiter.waits++
+
+	return nil
+}
+
+func TestCalculateBatch(t *testing.T) {
+	useFakeADP(t)
+
+	input := strings.Join([]string{
+		"salary, state,pay-frequency,deduction,deduction",
+		"120000,CA,monthly,,",
+		"120000,tx,monthly,401k=500,hsa=100",
+		",CA,,,",
+		"abc,CA,,,",
+		"60000,ZZ,,,",
+		"60000,CA,fortnightly,,",
+	}, "\n")
+
+	want := []string{
+		"salary,state,pay-frequency,deduction,deduction,net,gross,total_taxes,federal_taxes,state_taxes,local_taxes," +
+			"territory_taxes,error",
+		"120000,CA,monthly,,,7435.00,10000.00,2565.00,1765.00,800.00,0.00,0.00,",
+		"120000,tx,monthly,401k=500,hsa=100,7740.90,10000.00,1659.10,1659.10,0.00,0.00,0.00,",
+		",CA,,,,,,,,,,,salary or hourly-rate must be specified",
+		`abc,CA,,,,,,,,,,,"salary is not a valid float: strconv.ParseFloat: parsing ""abc"": invalid syntax"`,
+		"60000,ZZ,,,,,,,,,,,no jurisdiction found for code: ZZ",
+		"60000,CA,fortnightly,,,,,,,,,,invalid value for pay-frequency: invalid pay frequency: fortnightly",
+	}
+
+	var output bytes.Buffer
+
+	failed, err := calculateBatch(context.Background(), strings.NewReader(input), &output, &countingLimiter{})
+	if err != nil {
+		t.Fatalf("calculateBatch() error = %v", err)
+	}
+
+	if failed != 4 {
+		t.Errorf("calculateBatch() failed = %d, want 4", failed)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(want), lines)
+	}
+
+	for i, line := range lines {
+		if line != want[i] {
+			t.Errorf("line %d = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestCalculateBatchAddsCommandLineOptions(t *testing.T) {
+	useFakeADP(t)
+
+	err := globalOptions.payFrequency.Set("monthly")
+	if err != nil {
+		t.Fatalf("failed to set pay frequency: %s", err)
+	}
+
+	var output bytes.Buffer
+
+	_, err = calculateBatch(context.Background(), strings.NewReader("salary,state\n120000,CA\n"), &output,
+		&countingLimiter{})
+	if err != nil {
+		t.Fatalf("calculateBatch() error = %v", err)
+	}
+
+	want := "120000,CA,7435.00,10000.00,2565.00,1765.00,800.00,0.00,0.00,"
+	if lines := strings.Split(strings.TrimSpace(output.String()), "\n"); len(lines) != 2 || lines[1] != want {
+		t.Errorf("output = %q, want a row of %q", lines, want)
+	}
+}
+
+func TestCalculateBatchInvalidInput(t *testing.T) {
+	useFakeADP(t)
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"ragged row", "salary,state\n120000,CA\n60000\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := calculateBatch(context.Background(), strings.NewReader(test.input), &bytes.Buffer{},
+				&countingLimiter{})
+			if err == nil {
+				t.Error("calculateBatch() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestCalculateBatchWaitsForLimiter(t *testing.T) {
+	server := useFakeADP(t)
+	limiter := &countingLimiter{}
+
+	input := "salary,state\n120000,CA\nabc,CA\n60000,TX\n60000,NY\n"
+
+	failed, err := calculateBatch(context.Background(), strings.NewReader(input), &bytes.Buffer{}, limiter)
+	if err != nil {
+		t.Fatalf("calculateBatch() error = %v", err)
+	}
+
+	if failed != 1 {
+		t.Errorf("calculateBatch() failed = %d, want 1", failed)
+	}
+
+	// The row with an invalid salary fails before it is sent, so it does not wait.
+	if limiter.waits != 3 {
+		t.Errorf("limiter was waited on %d times, want 3", limiter.waits)
+	}
+
+	if count := server.CalculationCount(); count != 3 {
+		t.Errorf("fake ADP received %d calculations, want 3", count)
+	}
+}
+
+func TestRunBatchReadsStdin(t *testing.T) {
+	useFakeADP(t)
+
+	previousRateLimit := rateLimit
+	rateLimit = time.Millisecond
+
+	t.Cleanup(func() { rateLimit = previousRateLimit })
+
+	tests := []struct {
+		name     string
+		input    string
+		wantCode int
+		wantRows int
+	}{
+		{"success", "salary,state,pay-frequency\n120000,CA,monthly\n60000,TX,monthly\n", 0, 2},
+		{"failed row", "salary,state\n120000,CA\n60000,ZZ\n", 1, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			stdinPath := filepath.Join(dir, "stdin.csv")
+			stdoutPath := filepath.Join(dir, "stdout.csv")
+
+			err := os.WriteFile(stdinPath, []byte(test.input), 0o600)
+			if err != nil {
+				t.Fatalf("failed to write stdin: %s", err)
+			}
+
+			stdin, err := os.Open(stdinPath)
+			if err != nil {
+				t.Fatalf("failed to open stdin: %s", err)
+			}
+			defer stdin.Close()
+
+			stdout, err := os.Create(stdoutPath)
+			if err != nil {
+				t.Fatalf("failed to create stdout: %s", err)
+			}
+			defer stdout.Close()
+
+			previousStdin, previousStdout := os.Stdin, os.Stdout
+			os.Stdin, os.Stdout = stdin, stdout
+
+			code := runBatch("-")
+
+			os.Stdin, os.Stdout = previousStdin, previousStdout
+
+			if code != test.wantCode {
+				t.Errorf("runBatch() = %d, want %d", code, test.wantCode)
+			}
+
+			output, err := os.ReadFile(stdoutPath)
+			if err != nil {
+				t.Fatalf("failed to read stdout: %s", err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+			if len(lines) != test.wantRows+1 {
+				t.Errorf("got %d lines, want a header and %d rows: %q", len(lines), test.wantRows, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// printBreakdown writes a table of every entity in the response to the writer. Each earning and tax is printed on its
+// own row with its label, jurisdiction, and amount, followed by the gross, total taxes, deductions, and net income.
+func printBreakdown(writer io.Writer, resp *response.Response) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "SECTION\tLABEL\tJURISDICTION\tAMOUNT")
+
+	for _, entity := range resp.Earnings.Entities {
+		fmt.Fprintf(table, "Earnings\t%s\t\t%12.2f\n", entity.Label, entity.Amount)
+	}
+
+	fmt.Fprintf(table, "Gross\t\t\t%12.2f\n", resp.Gross.Amount)
+
+	sections := []struct {
+		name     string
+		entities response.TaxEntities
+	}{
+		{"Federal", resp.Taxes.Federal},
+		{"State", resp.Taxes.State},
+		{"Local", resp.Taxes.Local},
+		{"Territory", resp.Taxes.Territory},
+	}
+
+	for _, section := range sections {
+		for _, entity := range section.entities.Entities {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%12.2f\n",
+				section.name, entity.Label, entity.Jurisdiction.JurisdictionCode.Code, entity.Amount)
+		}
+	}
+
+	fmt.Fprintf(table, "Total taxes\t\t\t%12.2f\n", resp.Taxes.SummaryEntity.Amount)
+
+	for _, entity := range resp.Deductions.Entities {
+		fmt.Fprintf(table, "Deduction\t%s\t\t%12.2f\n", entity.Label, entity.Amount)
+	}
+
+	fmt.Fprintf(table, "Total deductions\t\t\t%12.2f\n", resp.Deductions.SummaryEntity.Amount)
+	fmt.Fprintf(table, "Net\t\t\t%12.2f\n", resp.Net.Amount)
+
+	return table.Flush()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/compare"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/response"
+	"golang.org/x/time/rate"
+)
+
+// runCompare calculates the salary in each of the states and prints the results from the highest net income to the
+// lowest as a table, CSV, or JSON to stdout. Options for the calculation may be given as flags before or after the
+// subcommand. It returns the exit code.
+func runCompare(args []string) int {
+	compareOptions := globalOptions.clone()
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+
+	states := flags.String("states", "", "comma separated codes of states to compare, or all for every state")
+	format := flags.String("format", "table", "output format, either table, csv, or json")
+	flags.DurationVar(&rateLimit, "rate-limit", rateLimit,
+		"requests to the ADP API are rate limited to one per this duration")
+	compareOptions.register(flags)
+
+	_ = flags.Parse(args)
+
+	salary, hasSalary, err := compareOptions.parseSalary(flags.Args())
+	if err != nil {
+		glog.Errorf("Invalid arguments: %s", err)
+
+		return 2
+	}
+
+	if err = validateCompareOptions(compareOptions); err != nil {
+		glog.Errorf("Invalid options: %s", err)
+
+		return 2
+	}
+
+	*format = strings.ToLower(*format)
+	if *format != "table" && *format != "csv" && *format != "json" {
+		glog.Errorf("Unsupported format: %s", *format)
+
+		return 2
+	}
+
+	ctx := context.Background()
+
+	stateCodes, err := compare.StateCodes(ctx, jurisdiction.DefaultRegistry, strings.Split(*states, ","))
+	if err != nil {
+		glog.Errorf("Failed to get states: %s", err)
+
+		return 2
+	}
+
+	results, err := compareStates(ctx, compareOptions, stateCodes, salary, hasSalary)
+	if err != nil {
+		glog.Errorf("Failed to compare states: %s", err)
+
+		return 2
+	}
+
+	err = writeComparison(os.Stdout, *format, results)
+	if err != nil {
+		glog.Errorf("Failed to write comparison: %s", err)
+
+		return 2
+	}
+
+	return 0
+}
+
+// validateCompareOptions returns an error if the options are inconsistent or have a state, since compare sets the
+// state itself.
+func validateCompareOptions(opts *options) error {
+	if opts.state != "" || opts.liveState != "" || opts.workState != "" {
+		return errors.New("-state, -live-state, and -work-state cannot be used with compare")
+	}
+
+	return opts.validate()
+}
+
+// compareStates calculates the salary with the options in each of the states, rate limited by -rate-limit. The salary
+// is only added if it was given, since hourly pay may be compared without one.
+func compareStates(
+	ctx context.Context, opts *options, states []string, salary float64, hasSalary bool,
+) ([]compare.Result, error) {
+	limiter := rate.NewLimiter(rate.Every(rateLimit), 1)
+
+	return compare.Run(ctx, jurisdiction.DefaultRegistry, states,
+		func(ctx context.Context, state string) (*response.Response, error) {
+			stateOptions := opts.clone()
+			stateOptions.state = state
+
+			builder := stateOptions.buildRequest(ctx)
+			if hasSalary {
+				stateOptions.withSalary(builder, salary)
+			}
+
+			return builder.WithLimiter(limiter).SendContext(ctx)
+		})
+}
+
+// writeComparison writes the results of a comparison to the writer in the format, which is either table, csv, or json.
+func writeComparison(writer io.Writer, format string, results []compare.Result) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(results)
+	case "csv":
+		return compare.WriteCSV(writer, results)
+	default:
+		return printComparison(writer, results)
+	}
+}
+
+// printComparison writes a table of the results of a comparison to the writer, one state per row.
+func printComparison(writer io.Writer, results []compare.Result) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "STATE\tNAME\tNET\tSTATE TAXES\tDIFFERENCE")
+
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%s\t%12.2f\t%12.2f\t%12.2f\n",
+			result.State, result.Name, result.Net, result.StateTaxes, result.Difference)
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/doctor"
+)
+
+// runDoctor checks that ADP's format still matches what taxcalc expects and prints the report to stdout. It returns
+// the exit code, which is 1 if any check failed or found a problem.
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+
+	_ = flags.Parse(args)
+
+	report := doctor.Run(context.Background(), doctor.Options{})
+
+	err := report.Write(os.Stdout)
+	if err != nil {
+		glog.Errorf("Failed to write report: %s", err)
+
+		return 2
+	}
+
+	if !report.OK() {
+		return 1
+	}
+
+	return 0
+}
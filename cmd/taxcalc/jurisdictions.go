@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+)
+
+// defaultSnapshotPath is the path of the embedded jurisdiction snapshot relative to the root of the repository.
+const defaultSnapshotPath = "internal/jurisdiction/snapshot.json"
+
+// runJurisdictions runs the jurisdictions subcommand with the arguments following it. It returns the exit code.
+func runJurisdictions(args []string) int {
+	if len(args) < 1 {
+		glog.Error("Jurisdictions subcommand must be specified")
+
+		fmt.Print(usage)
+
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		return runJurisdictionsList(args[1:])
+	case "refresh":
+		return runJurisdictionsRefresh(args[1:])
+	default:
+		glog.Errorf("Unknown jurisdictions subcommand: %s", args[0])
+
+		fmt.Print(usage)
+
+		return 2
+	}
+}
+
+// runJurisdictionsRefresh loads the jurisdictions from ADP and writes them to the snapshot file if they pass
+// [jurisdiction.Snapshot.Validate]. It returns the exit code.
+func runJurisdictionsRefresh(args []string) int {
+	flags := flag.NewFlagSet("jurisdictions refresh", flag.ExitOnError)
+	output := flags.String("o", defaultSnapshotPath, "path to write the jurisdiction snapshot to")
+
+	_ = flags.Parse(args)
+
+	snapshot, err := jurisdiction.FetchSnapshot()
+	if err != nil {
+		glog.Errorf("Failed to fetch jurisdictions: %s", err)
+
+		return 2
+	}
+
+	// The snapshot is the fallback when ADP cannot be reached, so one that is missing jurisdictions is never written.
+	if problems := snapshot.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			glog.Errorf("Invalid jurisdiction snapshot: %s", problem)
+		}
+
+		return 2
+	}
+
+	snapshotJSON, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		glog.Errorf("Failed to encode jurisdiction snapshot: %s", err)
+
+		return 2
+	}
+
+	err = os.WriteFile(*output, append(snapshotJSON, '\n'), 0o644)
+	if err != nil {
+		glog.Errorf("Failed to write jurisdiction snapshot: %s", err)
+
+		return 2
+	}
+
+	glog.Infof("Wrote %d jurisdictions from PCC version %s to %s",
+		len(snapshot.Jurisdictions), snapshot.PCCVersion, *output)
+
+	return 0
+}
+
+// runJurisdictionsList loads the jurisdictions and prints every one of them as a table or JSON to stdout. It returns
+// the exit code.
+func runJurisdictionsList(args []string) int {
+	flags := flag.NewFlagSet("jurisdictions list", flag.ExitOnError)
+	format := flags.String("format", "table", "output format, either table or json")
+
+	_ = flags.Parse(args)
+
+	*format = strings.ToLower(*format)
+	if *format != "table" && *format != "json" {
+		glog.Errorf("Unsupported format: %s", *format)
+
+		return 2
+	}
+
+	listing, err := jurisdiction.DefaultRegistry.List(context.Background())
+	if err != nil {
+		glog.Errorf("Failed to list jurisdictions: %s", err)
+
+		return 2
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(listing)
+	} else {
+		err = printJurisdictions(os.Stdout, listing)
+	}
+
+	if err != nil {
+		glog.Errorf("Failed to write jurisdictions: %s", err)
+
+		return 2
+	}
+
+	return 0
+}
+
+// printJurisdictions writes the PCC version of the listing followed by a table of its jurisdictions to the writer, one
+// jurisdiction per row.
+func printJurisdictions(writer io.Writer, listing *jurisdiction.Listing) error {
+	fmt.Fprintf(writer, "PCC version %s\n\n", listing.PCCVersion)
+
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "CODE\tNAME\tLEVEL\tPARENT\tID")
+
+	for _, entry := range listing.Jurisdictions {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", entry.Code, entry.Name, entry.Level, entry.Parent, entry.ID)
+	}
+
+	return table.Flush()
+}
@@ -1,19 +1,137 @@
 /*
 Taxcalc calculates the income tax for a salary. It takes a salary as a command line argument and calculates the net
-income less tax per pay period.
+income less tax per pay period. With -target-net, it instead calculates the gross pay per pay period needed to take home
+the target net income. Hourly pay may be given with -hourly-rate and -hours instead of or in addition to the salary.
+
+With -input, taxcalc calculates every row of a CSV file instead. The header row names the columns, which are salary
+and any of the flags below that set options for the calculation, such as state or deduction. A flag may be given in
+more than one column to repeat it, and empty cells are ignored. The options of each row override the ones from the
+command line, except for deductions and supplemental pay, which are added to them. The output is the input with the
+net income, gross income, total taxes, taxes for each type of jurisdiction, and any error appended to each row. If any
+row fails, the exit code is 1.
 
 Usage:
 
 	taxcalc [flags] salary
+	taxcalc [flags] -hourly-rate rate -hours hours [salary]
+	taxcalc [flags] -target-net net
+	taxcalc [flags] -input file.csv
+	taxcalc [flags] sweep -from salary -to salary -step amount [-format csv|json] [flags]
+	taxcalc [flags] compare -states codes|all [-format table|csv|json] [flags] salary
+	taxcalc jurisdictions list [-format table|json]
+	taxcalc jurisdictions refresh [-o snapshot.json]
+	taxcalc doctor
+
+The sweep command calculates every annual salary from -from to -to, inclusive, in increments of -step, which may be at
+most 60 salaries. For each salary, it prints the gross, net, and total taxes per pay period along with the effective tax
+rate and the marginal tax rate since the previous salary as CSV or, with -format json, as JSON. The flags below may be
+given before or after sweep, and requests are rate limited by -rate-limit.
+
+The compare command calculates the salary in each of the comma separated states given with -states, or every state if
+it is all. It prints the net income and state taxes per pay period for each state along with the difference in net
+income from the best state, sorted from the highest net income to the lowest. The flags below may be given before or
+after compare, except for -state, -live-state, and -work-state, and requests are rate limited by -rate-limit.
+
+The jurisdictions list command prints the code, name, level, parent state, and ID of every jurisdiction whose code may
+be used with -state, -local, -live-state, or -work-state, along with the PCC version that they were loaded from.
+
+The jurisdictions refresh command loads the current jurisdictions from ADP and writes them to the snapshot that is
+embedded in the binary as a fallback. Nothing is written if any state or the federal jurisdiction is missing. By
+default, it writes to internal/jurisdiction/snapshot.json, so it should be run from the root of the repository.
+
+The doctor command checks that ADP's format still matches what taxcalc expects. It checks that the jurisdictions scraped
+from ADP include the federal jurisdiction and every state along with the District of Columbia, that their IDs are
+UUIDs, and that every jurisdiction in the bundle was parsed. It then sends a sample calculation and checks that the
+response decodes without any unknown fields. It prints what changed, and the exit code is 1 if anything did.
 
 The flags are:
 
 	-s, -state string
 	        Calculate income tax for state in addition to federal income tax. This is a two letter abbreviation.
 
+	-l, -local string
+	        Comma separated codes of local jurisdictions such as cities, counties, or school districts to calculate
+	        income tax for. The state each local jurisdiction is in is added automatically.
+
+	-live-state string
+	        State lived in, as a two letter abbreviation. Use with -work-state when living and working in different
+	        states.
+
+	-work-state string
+	        State worked in, as a two letter abbreviation. Use with -live-state when living and working in different
+	        states.
+
 	-p, -pay-frequency string
-	        Output net income per pay frequency. Must be one of monthly, bi-weekly, weekly, or semi-monthly. If not
-	        specified, the default is monthly.
+	        Output net income per pay frequency. Must be one of daily, weekly, bi-weekly, semi-monthly, monthly,
+	        quarterly, semi-annual, or annual. The aliases biweekly, semimonthly, semiannual, annually, and yearly are
+	        also accepted. If not specified, the default is monthly.
+
+	-pay-date string
+	        Date of the paycheck as YYYY-MM-DD, which determines the tax year whose rules are used. Must be in a tax
+	        year from 2020 through next year. If not specified, the default is today.
+
+	-salary-frequency string
+	        Frequency of the salary, either annual or periodic for an amount per pay period. If not specified, the
+	        default is annual.
+
+	-hourly-rate float
+	        Hourly rate in dollars. When specified, the salary may be omitted. It is also the base rate for -overtime-hours
+	        and -doubletime-hours.
+
+	-hours float
+	        Regular hours worked per pay period at -hourly-rate.
+
+	-overtime-hours float
+	        Overtime hours worked per pay period, paid at 1.5 times -hourly-rate.
+
+	-doubletime-hours float
+	        Double time hours worked per pay period, paid at 2 times -hourly-rate.
+
+	-b, -breakdown
+	        Print a table of every earning, tax, and deduction along with the gross and net income instead of only the
+	        net income.
+
+	-target-net float
+	        Net income per pay period to calculate the required gross pay for. When specified, the salary must not be
+	        given and the gross pay per pay period is printed instead of the net income.
+
+	-i, -input string
+	        CSV file of salaries and options to calculate, or - to read from stdin. When specified, the salary must not
+	        be given.
+
+	-rate-limit duration
+	        Requests to the ADP API are rate limited to one per this duration with -input, sweep, or compare.
+	        Defaults to 1s.
+
+	-filing-status string
+	        Federal filing status from step 1(c) of the W4. Must be one of single, married, or head-of-household. If not
+	        specified, ADP's default is used.
+
+	-multiple-jobs
+	        Check the box in step 2(c) of the W4 for multiple jobs or a working spouse.
+
+	-dependents float
+	        Total dependents credit in dollars from step 3 of the W4.
+
+	-other-income float
+	        Annual other income in dollars from step 4(a) of the W4.
+
+	-w4-deductions float
+	        Annual deductions in dollars from step 4(b) of the W4.
+
+	-extra-withholding float
+	        Extra withholding in dollars per pay period from step 4(c) of the W4.
+
+	-deduction type=amount
+	        Deduction taken out of each paycheck. The type must be one of 401k, roth-401k, hsa, fsa, medical, dental, or
+	        garnishment. The amount is in dollars per pay period, or a percent of the gross pay if it ends with %. May be
+	        repeated.
+
+	-supplemental type=amount[:method]
+	        Supplemental pay in the pay period on top of the salary. The type must be one of bonus, commission, tips, or
+	        other. The amount is in dollars. The method is how federal income tax is withheld from it, either flat to
+	        withhold at the flat supplemental rate or aggregate to withhold as if it were regular pay. If not
+	        specified, the method is flat. May be repeated.
 
 	-h, -help
 	        Print this help message.
@@ -21,63 +139,202 @@ The flags are:
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/golang/glog"
-	"github.com/tslnc04/tax-calculator/internal/request"
 )
 
 //nolint:lll
 const usage = `Taxcalc calculates the income tax for a salary. It takes a salary as a command line argument and calculates the net
-income less tax per pay period.
+income less tax per pay period. With -target-net, it instead calculates the gross pay per pay period needed to take home
+the target net income. Hourly pay may be given with -hourly-rate and -hours instead of or in addition to the salary.
+
+With -input, taxcalc calculates every row of a CSV file instead. The header row names the columns, which are salary
+and any of the flags below that set options for the calculation, such as state or deduction. A flag may be given in
+more than one column to repeat it, and empty cells are ignored. The options of each row override the ones from the
+command line, except for deductions and supplemental pay, which are added to them. The output is the input with the
+net income, gross income, total taxes, taxes for each type of jurisdiction, and any error appended to each row. If any
+row fails, the exit code is 1.
 
 Usage:
 
 	taxcalc [flags] salary
+	taxcalc [flags] -hourly-rate rate -hours hours [salary]
+	taxcalc [flags] -target-net net
+	taxcalc [flags] -input file.csv
+	taxcalc [flags] sweep -from salary -to salary -step amount [-format csv|json] [flags]
+	taxcalc [flags] compare -states codes|all [-format table|csv|json] [flags] salary
+	taxcalc jurisdictions list [-format table|json]
+	taxcalc jurisdictions refresh [-o snapshot.json]
+	taxcalc doctor
+
+The sweep command calculates every annual salary from -from to -to, inclusive, in increments of -step, which may be at
+most 60 salaries. For each salary, it prints the gross, net, and total taxes per pay period along with the effective tax
+rate and the marginal tax rate since the previous salary as CSV or, with -format json, as JSON. The flags below may be
+given before or after sweep, and requests are rate limited by -rate-limit.
+
+The compare command calculates the salary in each of the comma separated states given with -states, or every state if
+it is all. It prints the net income and state taxes per pay period for each state along with the difference in net
+income from the best state, sorted from the highest net income to the lowest. The flags below may be given before or
+after compare, except for -state, -live-state, and -work-state, and requests are rate limited by -rate-limit.
+
+The jurisdictions list command prints the code, name, level, parent state, and ID of every jurisdiction whose code may
+be used with -state, -local, -live-state, or -work-state, along with the PCC version that they were loaded from.
+
+The jurisdictions refresh command loads the current jurisdictions from ADP and writes them to the snapshot that is
+embedded in the binary as a fallback. Nothing is written if any state or the federal jurisdiction is missing. By
+default, it writes to internal/jurisdiction/snapshot.json, so it should be run from the root of the repository.
+
+The doctor command checks that ADP's format still matches what taxcalc expects. It checks that the jurisdictions scraped
+from ADP include the federal jurisdiction and every state along with the District of Columbia, that their IDs are
+UUIDs, and that every jurisdiction in the bundle was parsed. It then sends a sample calculation and checks that the
+response decodes without any unknown fields. It prints what changed, and the exit code is 1 if anything did.
 
 The flags are:
 
 	-s, -state string
 	        Calculate income tax for state in addition to federal income tax. This is a two letter abbreviation.
 
+	-l, -local string
+	        Comma separated codes of local jurisdictions such as cities, counties, or school districts to calculate
+	        income tax for. The state each local jurisdiction is in is added automatically.
+
+	-live-state string
+	        State lived in, as a two letter abbreviation. Use with -work-state when living and working in different
+	        states.
+
+	-work-state string
+	        State worked in, as a two letter abbreviation. Use with -live-state when living and working in different
+	        states.
+
 	-p, -pay-frequency string
-	        Output net income per pay frequency. Must be one of monthly, bi-weekly, weekly, or semi-monthly. If not
-	        specified, the default is monthly.
+	        Output net income per pay frequency. Must be one of daily, weekly, bi-weekly, semi-monthly, monthly,
+	        quarterly, semi-annual, or annual. The aliases biweekly, semimonthly, semiannual, annually, and yearly are
+	        also accepted. If not specified, the default is monthly.
+
+	-pay-date string
+	        Date of the paycheck as YYYY-MM-DD, which determines the tax year whose rules are used. Must be in a tax
+	        year from 2020 through next year. If not specified, the default is today.
+
+	-salary-frequency string
+	        Frequency of the salary, either annual or periodic for an amount per pay period. If not specified, the
+	        default is annual.
+
+	-hourly-rate float
+	        Hourly rate in dollars. When specified, the salary may be omitted. It is also the base rate for -overtime-hours
+	        and -doubletime-hours.
+
+	-hours float
+	        Regular hours worked per pay period at -hourly-rate.
+
+	-overtime-hours float
+	        Overtime hours worked per pay period, paid at 1.5 times -hourly-rate.
+
+	-doubletime-hours float
+	        Double time hours worked per pay period, paid at 2 times -hourly-rate.
+
+	-b, -breakdown
+	        Print a table of every earning, tax, and deduction along with the gross and net income instead of only the
+	        net income.
+
+	-target-net float
+	        Net income per pay period to calculate the required gross pay for. When specified, the salary must not be
+	        given and the gross pay per pay period is printed instead of the net income.
+
+	-i, -input string
+	        CSV file of salaries and options to calculate, or - to read from stdin. When specified, the salary must not
+	        be given.
+
+	-rate-limit duration
+	        Requests to the ADP API are rate limited to one per this duration with -input, sweep, or compare.
+	        Defaults to 1s.
+
+	-filing-status string
+	        Federal filing status from step 1(c) of the W4. Must be one of single, married, or head-of-household. If not
+	        specified, ADP's default is used.
+
+	-multiple-jobs
+	        Check the box in step 2(c) of the W4 for multiple jobs or a working spouse.
+
+	-dependents float
+	        Total dependents credit in dollars from step 3 of the W4.
+
+	-other-income float
+	        Annual other income in dollars from step 4(a) of the W4.
+
+	-w4-deductions float
+	        Annual deductions in dollars from step 4(b) of the W4.
+
+	-extra-withholding float
+	        Extra withholding in dollars per pay period from step 4(c) of the W4.
+
+	-deduction type=amount
+	        Deduction taken out of each paycheck. The type must be one of 401k, roth-401k, hsa, fsa, medical, dental, or
+	        garnishment. The amount is in dollars per pay period, or a percent of the gross pay if it ends with %. May be
+	        repeated.
+
+	-supplemental type=amount[:method]
+	        Supplemental pay in the pay period on top of the salary. The type must be one of bonus, commission, tips, or
+	        other. The amount is in dollars. The method is how federal income tax is withheld from it, either flat to
+	        withhold at the flat supplemental rate or aggregate to withhold as if it were regular pay. If not
+	        specified, the method is flat. May be repeated.
 
 	-h, -help
 	        Print this help message.
 `
 
 var (
-	help         bool
-	state        string
-	payFrequency request.PayFrequencyCode
+	breakdown     bool
+	help          bool
+	targetNet     float64
+	input         string
+	rateLimit     time.Duration
+	globalOptions options
 )
 
 func init() {
 	const (
-		helpUsage         = "print this help message"
-		stateUsage        = "state to calculate income tax for as a two letter abbreviation"
-		payFrequencyUsage = "pay frequency to use, either monthly, bi-weekly, weekly, or semi-monthly"
+		breakdownUsage = "print a table of every earning, tax, and deduction instead of only the net income"
+		helpUsage      = "print this help message"
+		targetNetUsage = "net income per pay period to calculate the required gross pay for"
+		inputUsage     = "CSV file of salaries and options to calculate, or - for stdin"
+		rateLimitUsage = "requests to the ADP API are rate limited to one per this duration with -input, sweep, or compare"
+
+		defaultRateLimit = time.Second
 	)
 
+	flag.BoolVar(&breakdown, "breakdown", false, breakdownUsage)
+	flag.BoolVar(&breakdown, "b", false, breakdownUsage+" (shorthand)")
+
 	flag.BoolVar(&help, "help", false, helpUsage)
 	flag.BoolVar(&help, "h", false, helpUsage+" (shorthand)")
 
-	flag.StringVar(&state, "state", "", stateUsage)
-	flag.StringVar(&state, "s", "", stateUsage+" (shorthand)")
+	flag.Float64Var(&targetNet, "target-net", 0, targetNetUsage)
 
-	flag.Var(&payFrequency, "pay-frequency", payFrequencyUsage)
-	flag.Var(&payFrequency, "p", payFrequencyUsage+" (shorthand)")
+	flag.StringVar(&input, "input", "", inputUsage)
+	flag.StringVar(&input, "i", "", inputUsage+" (shorthand)")
+
+	flag.DurationVar(&rateLimit, "rate-limit", defaultRateLimit, rateLimitUsage)
+
+	globalOptions.register(flag.CommandLine)
 
 	// Tell glog to log to stderr.
 	_ = flag.Set("logtostderr", "true")
 }
 
+// subcommands are the functions that run each subcommand with the arguments after it. Each returns the exit code for
+// the program.
+var subcommands = map[string]func(args []string) int{
+	"jurisdictions": runJurisdictions,
+	"doctor":        runDoctor,
+	"compare":       runCompare,
+	"sweep":         runSweep,
+}
+
 func main() {
 	flag.Parse()
 
@@ -87,37 +344,108 @@ func main() {
 		os.Exit(0)
 	}
 
-	if flag.NArg() != 1 {
-		glog.Error("Salary must be specified")
-
-		fmt.Print(usage)
+	if run, ok := subcommands[flag.Arg(0)]; ok {
+		os.Exit(run(flag.Args()[1:]))
+	}
 
-		os.Exit(2)
+	if input != "" {
+		os.Exit(runBatch(input))
 	}
 
-	salary, err := strconv.ParseFloat(flag.Arg(0), 64)
-	if err != nil {
-		glog.Errorf("Failed to parse salary: %s", err)
+	if err := globalOptions.validate(); err != nil {
+		glog.Errorf("Invalid options: %s", err)
 
 		os.Exit(2)
 	}
 
-	builder := request.NewBuilder().WithSalary(salary, request.AnnualSalaryFrequency).WithPayFrequency(payFrequency)
+	if isFlagSet("target-net") {
+		os.Exit(runNetToGross())
+	}
+
+	os.Exit(runCalculation())
+}
 
-	if state != "" {
-		state = strings.ToUpper(state)
+// runCalculation calculates and prints the net income per pay period for the salary or hourly pay from the command
+// line. It returns the exit code for the program.
+func runCalculation() int {
+	salary, hasSalary, err := globalOptions.parseSalary(flag.Args())
+	if err != nil {
+		glog.Errorf("Invalid arguments: %s", err)
 
-		glog.V(10).Infof("Adding state: %s", state)
+		fmt.Print(usage)
 
-		builder.WithJurisdictionsByCode(state)
+		return 2
+	}
+
+	builder := globalOptions.buildRequest(context.Background())
+	if hasSalary {
+		globalOptions.withSalary(builder, salary)
 	}
 
 	response, err := builder.Send()
 	if err != nil {
 		glog.Errorf("Failed to send request: %s", err)
 
-		os.Exit(2)
+		return 2
+	}
+
+	if breakdown {
+		err = printBreakdown(os.Stdout, response)
+		if err != nil {
+			glog.Errorf("Failed to print breakdown: %s", err)
+
+			return 2
+		}
+
+		return 0
 	}
 
 	fmt.Printf("%.2f\n", response.Net.Amount)
+
+	return 0
+}
+
+// runNetToGross calculates and prints the gross pay per pay period needed for the target net income from the flags. It
+// returns the exit code for the program.
+func runNetToGross() int {
+	if flag.NArg() != 0 {
+		glog.Error("Salary must not be specified with -target-net")
+
+		return 2
+	}
+
+	gross, response, err := globalOptions.buildRequest(context.Background()).SendNetToGross(targetNet)
+	if err != nil {
+		glog.Errorf("Failed to calculate gross pay: %s", err)
+
+		return 2
+	}
+
+	if breakdown {
+		err = printBreakdown(os.Stdout, response)
+		if err != nil {
+			glog.Errorf("Failed to print breakdown: %s", err)
+
+			return 2
+		}
+
+		return 0
+	}
+
+	fmt.Printf("%.2f\n", gross)
+
+	return 0
+}
+
+// isFlagSet returns whether the flag with the given name was set on the command line.
+func isFlagSet(name string) bool {
+	set := false
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
 }
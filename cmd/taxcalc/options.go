@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/request"
+)
+
+// options are the options for a calculation that can be set with flags, everything except for the salary. The salary
+// frequency determines how the salary given by the caller is interpreted. The API URL and registry are not flags and
+// default to the real ADP, but tests point them at a fake one.
+type options struct {
+	apiURL           string
+	registry         *jurisdiction.Registry
+	salaryFrequency  request.SalaryFrequency
+	hourlyRate       float64
+	hours            float64
+	overtimeHours    float64
+	doubletimeHours  float64
+	state            string
+	locals           string
+	liveState        string
+	workState        string
+	payFrequency     request.PayFrequencyCode
+	payDate          payDate
+	filingStatus     request.FilingStatus
+	multipleJobs     bool
+	dependents       float64
+	otherIncome      float64
+	w4Deductions     float64
+	extraWithholding float64
+	deductions       request.DeductionSpecs
+	supplementalPay  request.SupplementalPaySpecs
+}
+
+// register defines a flag for each option on the flag set. The current values of the options are used as the defaults,
+// so options copied from another set of options keep their values unless the flags are set.
+func (opts *options) register(flags *flag.FlagSet) {
+	const (
+		salaryFrequencyUsage = "frequency of the salary, either annual or periodic for an amount per pay period"
+		hourlyRateUsage      = "hourly rate in dollars, which is also the base rate of overtime and double time"
+		hoursUsage           = "regular hours worked per pay period at the hourly rate"
+		overtimeHoursUsage   = "overtime hours worked per pay period at 1.5 times the hourly rate"
+		doubletimeHoursUsage = "double time hours worked per pay period at 2 times the hourly rate"
+
+		stateUsage        = "state to calculate income tax for as a two letter abbreviation"
+		localUsage        = "comma separated codes of local jurisdictions to calculate income tax for"
+		liveStateUsage    = "state lived in as a two letter abbreviation"
+		workStateUsage    = "state worked in as a two letter abbreviation"
+		payFrequencyUsage = "pay frequency to use, such as weekly, bi-weekly, semi-monthly, or monthly"
+		payDateUsage      = "date of the paycheck as YYYY-MM-DD, which determines the tax year"
+
+		filingStatusUsage     = "federal filing status, either single, married, or head-of-household"
+		multipleJobsUsage     = "check the W4 box for multiple jobs or a working spouse"
+		dependentsUsage       = "total dependents credit in dollars from the W4"
+		otherIncomeUsage      = "annual other income in dollars from the W4"
+		w4DeductionsUsage     = "annual deductions in dollars from the W4"
+		extraWithholdingUsage = "extra withholding in dollars per pay period from the W4"
+
+		deductionUsage    = "deduction of the form type=amount, or type=percent% for a percent of gross pay (repeatable)"
+		supplementalUsage = "supplemental pay of the form type=amount, or type=amount:method to set the method (repeatable)"
+	)
+
+	flags.Var(&opts.salaryFrequency, "salary-frequency", salaryFrequencyUsage)
+	flags.Float64Var(&opts.hourlyRate, "hourly-rate", opts.hourlyRate, hourlyRateUsage)
+	flags.Float64Var(&opts.hours, "hours", opts.hours, hoursUsage)
+	flags.Float64Var(&opts.overtimeHours, "overtime-hours", opts.overtimeHours, overtimeHoursUsage)
+	flags.Float64Var(&opts.doubletimeHours, "doubletime-hours", opts.doubletimeHours, doubletimeHoursUsage)
+
+	flags.StringVar(&opts.state, "state", opts.state, stateUsage)
+	flags.StringVar(&opts.state, "s", opts.state, stateUsage+" (shorthand)")
+
+	flags.StringVar(&opts.locals, "local", opts.locals, localUsage)
+	flags.StringVar(&opts.locals, "l", opts.locals, localUsage+" (shorthand)")
+
+	flags.StringVar(&opts.liveState, "live-state", opts.liveState, liveStateUsage)
+	flags.StringVar(&opts.workState, "work-state", opts.workState, workStateUsage)
+
+	flags.Var(&opts.payFrequency, "pay-frequency", payFrequencyUsage)
+	flags.Var(&opts.payFrequency, "p", payFrequencyUsage+" (shorthand)")
+
+	flags.Var(&opts.payDate, "pay-date", payDateUsage)
+
+	flags.Var(&opts.filingStatus, "filing-status", filingStatusUsage)
+	flags.BoolVar(&opts.multipleJobs, "multiple-jobs", opts.multipleJobs, multipleJobsUsage)
+	flags.Float64Var(&opts.dependents, "dependents", opts.dependents, dependentsUsage)
+	flags.Float64Var(&opts.otherIncome, "other-income", opts.otherIncome, otherIncomeUsage)
+	flags.Float64Var(&opts.w4Deductions, "w4-deductions", opts.w4Deductions, w4DeductionsUsage)
+	flags.Float64Var(&opts.extraWithholding, "extra-withholding", opts.extraWithholding, extraWithholdingUsage)
+
+	flags.Var(&opts.deductions, "deduction", deductionUsage)
+	flags.Var(&opts.supplementalPay, "supplemental", supplementalUsage)
+}
+
+// clone returns a copy of the options that can be changed without affecting the original.
+func (opts *options) clone() *options {
+	clone := *opts
+	clone.deductions = slices.Clone(opts.deductions)
+	clone.supplementalPay = slices.Clone(opts.supplementalPay)
+
+	return &clone
+}
+
+// validate returns an error if the options are inconsistent. Hours worked require an hourly rate to be paid at.
+func (opts *options) validate() error {
+	if opts.hourlyRate == 0 && (opts.hours != 0 || opts.overtimeHours != 0 || opts.doubletimeHours != 0) {
+		return errors.New("hourly-rate must be specified with hours, overtime-hours, or doubletime-hours")
+	}
+
+	return nil
+}
+
+// buildRequest creates a new request builder with the options. The salary is left for the caller to add with
+// [options.withSalary]. The context is used if the jurisdictions need to be loaded.
+func (opts *options) buildRequest(ctx context.Context) *request.Builder {
+	apiURL := opts.apiURL
+	if apiURL == "" {
+		apiURL = request.APIURL
+	}
+
+	builder := request.NewBuilder(apiURL).WithRegistry(opts.registry)
+
+	opts.addPay(builder)
+	opts.addJurisdictions(ctx, builder)
+	opts.addW4(builder)
+
+	builder.WithDeductionSpecs(opts.deductions...)
+	builder.WithSupplementalPaySpecs(opts.supplementalPay...)
+
+	return builder
+}
+
+// addPay adds the pay frequency, hourly pay, and pay date options to the builder.
+func (opts *options) addPay(builder *request.Builder) {
+	if opts.payFrequency != (request.PayFrequencyCode{}) {
+		builder.WithPayFrequency(opts.payFrequency)
+	}
+
+	if opts.hourlyRate != 0 {
+		glog.V(10).Infof("Adding hourly rate of %.2f for %.2f hours", opts.hourlyRate, opts.hours)
+
+		builder.WithHourly(opts.hours, opts.hourlyRate)
+	}
+
+	if opts.overtimeHours != 0 {
+		builder.WithOvertime(opts.overtimeHours, opts.hourlyRate)
+	}
+
+	if opts.doubletimeHours != 0 {
+		builder.WithDoubleTime(opts.doubletimeHours, opts.hourlyRate)
+	}
+
+	if !opts.payDate.IsZero() {
+		builder.WithPayDate(opts.payDate.Time)
+	}
+}
+
+// addJurisdictions adds the state, local, lived in, and worked in jurisdictions to the builder, loading them with the
+// context if needed.
+func (opts *options) addJurisdictions(ctx context.Context, builder *request.Builder) {
+	if opts.state != "" {
+		state := strings.ToUpper(opts.state)
+
+		glog.V(10).Infof("Adding state: %s", state)
+
+		builder.WithJurisdictionsByCode(ctx, state)
+	}
+
+	if opts.locals != "" {
+		glog.V(10).Infof("Adding local jurisdictions: %s", opts.locals)
+
+		builder.WithJurisdictionsByCode(ctx, strings.Split(opts.locals, ",")...)
+	}
+
+	if opts.liveState != "" {
+		liveState := strings.ToUpper(opts.liveState)
+
+		glog.V(10).Infof("Adding lived in state: %s", liveState)
+
+		builder.WithLivedInJurisdictionsByCode(ctx, liveState)
+	}
+
+	if opts.workState != "" {
+		workState := strings.ToUpper(opts.workState)
+
+		glog.V(10).Infof("Adding worked in state: %s", workState)
+
+		builder.WithWorkedInJurisdictionsByCode(ctx, workState)
+	}
+}
+
+// addW4 adds the W4 options to the builder. Options that are not set are left out so that ADP's defaults are used for
+// them.
+func (opts *options) addW4(builder *request.Builder) {
+	if opts.filingStatus != (request.FilingStatus{}) {
+		builder.WithFilingStatus(opts.filingStatus)
+	}
+
+	if opts.multipleJobs {
+		builder.WithMultipleJobs(opts.multipleJobs)
+	}
+
+	if opts.dependents != 0 {
+		builder.WithDependentsAmount(opts.dependents)
+	}
+
+	if opts.otherIncome != 0 {
+		builder.WithOtherIncome(opts.otherIncome)
+	}
+
+	if opts.w4Deductions != 0 {
+		builder.WithW4Deductions(opts.w4Deductions)
+	}
+
+	if opts.extraWithholding != 0 {
+		builder.WithExtraWithholding(opts.extraWithholding)
+	}
+}
+
+// parseSalary parses the salary from the arguments left after the flags. The salary may only be omitted if there is
+// an hourly rate, and the returned bool reports whether it was given.
+func (opts *options) parseSalary(args []string) (float64, bool, error) {
+	if len(args) > 1 || (len(args) == 0 && opts.hourlyRate == 0) {
+		return 0, false, errors.New("salary or -hourly-rate must be specified")
+	}
+
+	if len(args) == 0 {
+		return 0, false, nil
+	}
+
+	salary, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse salary: %w", err)
+	}
+
+	return salary, true, nil
+}
+
+// withSalary adds the salary to the builder with the salary frequency from the options, which defaults to annual.
+func (opts *options) withSalary(builder *request.Builder, salary float64) *request.Builder {
+	frequency := opts.salaryFrequency
+	if frequency == "" {
+		frequency = request.AnnualSalaryFrequency
+	}
+
+	return builder.WithSalary(salary, frequency)
+}
+
+// payDate is a flag value for the date of a paycheck in the YYYY-MM-DD format. The zero value means it was not set.
+type payDate struct {
+	time.Time
+}
+
+func (date *payDate) String() string {
+	if date.IsZero() {
+		return ""
+	}
+
+	return date.Format(time.DateOnly)
+}
+
+func (date *payDate) Set(value string) error {
+	parsed, err := request.ParsePayDate(value)
+	if err != nil {
+		return err
+	}
+
+	date.Time = parsed
+
+	return nil
+}
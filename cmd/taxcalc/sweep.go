@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/request"
+	"github.com/tslnc04/tax-calculator/internal/response"
+	"github.com/tslnc04/tax-calculator/internal/sweep"
+	"golang.org/x/time/rate"
+)
+
+// runSweep calculates the taxes for a range of salaries and writes a point for each as CSV or JSON to stdout. Options
+// for the calculation may be given as flags before or after the subcommand, except that the salaries are annual, so
+// -salary-frequency may not be periodic. It returns the exit code.
+func runSweep(args []string) int {
+	sweepOptions := globalOptions.clone()
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	rng := sweep.Range{}
+
+	flags.Float64Var(&rng.From, "from", 0, "annual salary to start the sweep at")
+	flags.Float64Var(&rng.To, "to", 0, "annual salary to end the sweep at, inclusive")
+	flags.Float64Var(&rng.Step, "step", 0, "amount to increase the annual salary by between points")
+	format := flags.String("format", "csv", "output format, either csv or json")
+	flags.DurationVar(&rateLimit, "rate-limit", rateLimit,
+		"requests to the ADP API are rate limited to one per this duration")
+	sweepOptions.register(flags)
+
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		glog.Errorf("Unexpected arguments to sweep: %v", flags.Args())
+
+		return 2
+	}
+
+	if err := sweepOptions.validate(); err != nil {
+		glog.Errorf("Invalid options: %s", err)
+
+		return 2
+	}
+
+	if sweepOptions.salaryFrequency == request.PeriodicSalaryFrequency {
+		glog.Error("-salary-frequency must be annual for a sweep")
+
+		return 2
+	}
+
+	*format = strings.ToLower(*format)
+	if *format != "csv" && *format != "json" {
+		glog.Errorf("Unsupported format: %s", *format)
+
+		return 2
+	}
+
+	limiter := rate.NewLimiter(rate.Every(rateLimit), 1)
+
+	points, err := sweep.Run(context.Background(), rng,
+		func(ctx context.Context, salary float64) (*response.Response, error) {
+			return sweepOptions.buildRequest(ctx).WithSalary(salary, request.AnnualSalaryFrequency).
+				WithLimiter(limiter).SendContext(ctx)
+		})
+	if err != nil {
+		glog.Errorf("Failed to run sweep: %s", err)
+
+		return 2
+	}
+
+	err = writeSweep(os.Stdout, *format, points)
+	if err != nil {
+		glog.Errorf("Failed to write sweep: %s", err)
+
+		return 2
+	}
+
+	return 0
+}
+
+// writeSweep writes the points of a sweep to the writer as JSON if the format is json and as CSV otherwise.
+func writeSweep(writer io.Writer, format string, points []sweep.Point) error {
+	if format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(points)
+	}
+
+	return sweep.WriteCSV(writer, points)
+}
@@ -1,6 +1,24 @@
 /*
 Taxcalcd is a web server that calculates the income tax for a salary. It takes a salary, pay frequency, and state as
-query parameters and returns the net income in CSV format.
+query parameters and returns the net income in CSV format, or the full breakdown of the calculation in JSON format.
+Hourly pay may be given with the hourly-rate, hours, overtime-hours, and doubletime-hours query parameters instead of
+or in addition to the salary.
+The /api/v1/net-to-gross endpoint instead takes the target net income per pay period as the net query parameter and
+returns the gross pay per pay period needed to take it home.
+The /api/v1/batch endpoint accepts a POST with a JSON array or CSV file of scenarios using the same parameters and
+returns the result of each scenario in order. A batch may have at most 60 scenarios.
+The /api/v1/sweep endpoint takes from, to, and step query parameters instead of a salary and returns the taxes along
+with the effective and marginal tax rates for each annual salary in the range. A range may have at most 60 salaries.
+The /api/v1/compare endpoint takes a comma separated list of up to 10 state codes, or all, as the states query parameter
+instead of a state and returns the net income in each state sorted from the highest to the lowest.
+The /api/v1/jurisdictions endpoint returns the ID, code, name, and level of every jurisdiction whose code may be used as
+a state or local, along with the PCC version that they were loaded from.
+The jurisdictions are refreshed periodically so that a new PCC version from ADP is picked up without a restart, and the
+response cache is purged when the version changes. The current PCC version is returned by the jurisdictions endpoint
+and, if -admin_port is given, exported as pcc_version by the /debug/vars endpoint on that port along with the standard
+expvar variables.
+At startup, taxcalcd checks that ADP's format still matches what it expects, the same as taxcalc doctor, and logs a
+warning for anything that changed.
 
 Usage:
 
@@ -8,24 +26,51 @@ Usage:
 
 The flags are:
 
+	-admin_port string
+		Port to serve the /debug/vars endpoint on, separately from the API so that it need not be exposed. If not
+		specified, it is not served.
+
+	-api_url string
+		URL of the ADP API that calculations are sent to. Defaults to the real ADP API.
+
+	-cache_dir string
+		Directory to keep the response cache in so that it survives restarts. If not specified, the response cache is
+		kept in memory. Cached responses expire at the start of the year after their tax year either way.
+
 	-c, -cache_size int
-		Number of entries to keep in the response cache. Defaults to 1000.
+		Number of entries to keep in the response cache, in memory or in -cache_dir. Defaults to 1000.
 
 	-h, -help
 		Print this help message.
 
+	-jurisdiction_url string
+		Base URL of ADP's PWC, where the jurisdictions are loaded from. Defaults to the real PWC.
+
 	-log_dir string
 		Directory to write logs to. Defaults to a temporary directory.
 
 	-p, -port string
 		Port to listen on. Defaults to 8080.
 
+	-r, -rate_limit duration
+		Requests to the ADP API are rate limited to one per this duration. Defaults to 1s.
+
+	-refresh_interval duration
+		How often to check ADP for a new PCC version and reload the jurisdictions if it changed. The jurisdictions are
+		also loaded from ADP at startup unless refreshing is disabled with a value of 0. Defaults to 1h.
+
+	-startup_check
+		Check that ADP's format still matches what taxcalcd expects at startup and log a warning for anything that
+		changed. Defaults to true.
+
 	-v int
 		Maximum log verbosity. Defaults to 0.
 */
 package main
 
 import (
+	"context"
+	"expvar"
 	"flag"
 	"net/http"
 	"os"
@@ -33,12 +78,33 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/doctor"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/request"
 	"github.com/tslnc04/tax-calculator/internal/server"
 )
 
 //nolint:lll
 const usage = `Taxcalcd is a web server that calculates the income tax for a salary. It takes a salary, pay frequency, and state as
-query parameters and returns the net income in CSV format.
+query parameters and returns the net income in CSV format, or the full breakdown of the calculation in JSON format.
+Hourly pay may be given with the hourly-rate, hours, overtime-hours, and doubletime-hours query parameters instead of
+or in addition to the salary.
+The /api/v1/net-to-gross endpoint instead takes the target net income per pay period as the net query parameter and
+returns the gross pay per pay period needed to take it home.
+The /api/v1/batch endpoint accepts a POST with a JSON array or CSV file of scenarios using the same parameters and
+returns the result of each scenario in order. A batch may have at most 60 scenarios.
+The /api/v1/sweep endpoint takes from, to, and step query parameters instead of a salary and returns the taxes along
+with the effective and marginal tax rates for each annual salary in the range. A range may have at most 60 salaries.
+The /api/v1/compare endpoint takes a comma separated list of up to 10 state codes, or all, as the states query parameter
+instead of a state and returns the net income in each state sorted from the highest to the lowest.
+The /api/v1/jurisdictions endpoint returns the ID, code, name, and level of every jurisdiction whose code may be used as
+a state or local, along with the PCC version that they were loaded from.
+The jurisdictions are refreshed periodically so that a new PCC version from ADP is picked up without a restart, and the
+response cache is purged when the version changes. The current PCC version is returned by the jurisdictions endpoint
+and, if -admin_port is given, exported as pcc_version by the /debug/vars endpoint on that port along with the standard
+expvar variables.
+At startup, taxcalcd checks that ADP's format still matches what it expects, the same as taxcalc doctor, and logs a
+warning for anything that changed.
 
 Usage:
 
@@ -46,12 +112,26 @@ Usage:
 
 The flags are:
 
+	-admin_port string
+		Port to serve the /debug/vars endpoint on, separately from the API so that it need not be exposed. If not
+		specified, it is not served.
+
+	-api_url string
+		URL of the ADP API that calculations are sent to. Defaults to the real ADP API.
+
+	-cache_dir string
+		Directory to keep the response cache in so that it survives restarts. If not specified, the response cache is
+		kept in memory. Cached responses expire at the start of the year after their tax year either way.
+
 	-c, -cache_size int
-		Number of entries to keep in the response cache. Defaults to 1000.
+		Number of entries to keep in the response cache, in memory or in -cache_dir. Defaults to 1000.
 
 	-h, -help
 		Print this help message.
 
+	-jurisdiction_url string
+		Base URL of ADP's PWC, where the jurisdictions are loaded from. Defaults to the real PWC.
+
 	-log_dir string
 		Directory to write logs to. Defaults to a temporary directory.
 
@@ -61,30 +141,60 @@ The flags are:
 	-r, -rate_limit duration
 		Requests to the ADP API are rate limited to one per this duration. Defaults to 1s.
 
+	-refresh_interval duration
+		How often to check ADP for a new PCC version and reload the jurisdictions if it changed. The jurisdictions are
+		also loaded from ADP at startup unless refreshing is disabled with a value of 0. Defaults to 1h.
+
+	-startup_check
+		Check that ADP's format still matches what taxcalcd expects at startup and log a warning for anything that
+		changed. Defaults to true.
+
 	-v int
 		Maximum log verbosity. Defaults to 0.
 `
 
 var (
-	cacheSize int
-	help      bool
-	port      string
-	rateLimit time.Duration
+	adminPort       string
+	apiURL          string
+	jurisdictionURL string
+	cacheDir        string
+	cacheSize       int
+	help            bool
+	port            string
+	rateLimit       time.Duration
+	refreshInterval time.Duration
+	startupCheck    bool
 )
 
 func init() {
 	const (
-		cacheUsage     = "number of entries to keep in the response cache"
+		adminPortUsage = "port to serve /debug/vars on, or empty to not serve it"
+		apiURLUsage    = "URL of the ADP API that calculations are sent to"
+		pwcURLUsage    = "base URL of ADP's PWC, where the jurisdictions are loaded from"
+		cacheDirUsage  = "directory to keep the response cache in so that it survives restarts"
+		cacheUsage     = "number of entries to keep in the response cache, in memory or in -cache_dir"
 		helpUsage      = "print this help message"
 		portUsage      = "port to listen on"
 		rateLimitUsage = "requests to the ADP API are rate limited to one per this duration"
+		refreshUsage   = "how often to check ADP for a new PCC version, or 0 to disable refreshing"
+		checkUsage     = "check that ADP's format still matches what taxcalcd expects at startup"
 
 		defaultCacheSize = 1000
 		defaultHelp      = false
 		defaultPort      = ":8080"
 		defaultRateLimit = time.Second
+		defaultRefresh   = time.Hour
+		defaultCheck     = true
 	)
 
+	flag.StringVar(&adminPort, "admin_port", "", adminPortUsage)
+
+	flag.StringVar(&apiURL, "api_url", request.APIURL, apiURLUsage)
+
+	flag.StringVar(&jurisdictionURL, "jurisdiction_url", jurisdiction.DefaultBaseURL, pwcURLUsage)
+
+	flag.StringVar(&cacheDir, "cache_dir", "", cacheDirUsage)
+
 	flag.IntVar(&cacheSize, "cache_size", defaultCacheSize, cacheUsage)
 	flag.IntVar(&cacheSize, "c", defaultCacheSize, cacheUsage+" (shorthand)")
 
@@ -97,6 +207,10 @@ func init() {
 	flag.DurationVar(&rateLimit, "rate_limit", defaultRateLimit, rateLimitUsage)
 	flag.DurationVar(&rateLimit, "r", defaultRateLimit, rateLimitUsage+" (shorthand)")
 
+	flag.DurationVar(&refreshInterval, "refresh_interval", defaultRefresh, refreshUsage)
+
+	flag.BoolVar(&startupCheck, "startup_check", defaultCheck, checkUsage)
+
 	// Tell glog to log to stderr as well as the log file.
 	_ = flag.Set("alsologtostderr", "true")
 }
@@ -110,17 +224,40 @@ func main() {
 		return
 	}
 
-	if !strings.HasPrefix(port, ":") {
-		port = ":" + port
-	}
+	port = normalizePort(port)
+	loader := &jurisdiction.Loader{BaseURL: jurisdictionURL}
 
-	mux, err := server.NewRequestMux(cacheSize, rateLimit)
+	requestHandler, err := server.NewRequestHandler(server.Options{
+		CacheDir:  cacheDir,
+		CacheSize: cacheSize,
+		RateLimit: rateLimit,
+		APIURL:    apiURL,
+		Registry:  jurisdiction.NewRegistry(loader),
+	})
 	if err != nil {
-		glog.Errorf("Failed to create request mux: %s", err)
+		glog.Errorf("Failed to create request handler: %s", err)
 
 		os.Exit(2)
 	}
 
+	if adminPort != "" {
+		expvar.Publish("pcc_version", expvar.Func(func() any {
+			return requestHandler.PCCVersion()
+		}))
+
+		go serveAdmin(normalizePort(adminPort))
+	}
+
+	mux := server.NewServeMux(requestHandler)
+
+	if refreshInterval > 0 {
+		go requestHandler.RefreshPeriodically(context.Background(), refreshInterval)
+	}
+
+	if startupCheck {
+		go runStartupCheck(loader, requestHandler)
+	}
+
 	glog.V(10).Infof("Starting server on port %s", port)
 
 	err = http.ListenAndServe(port, mux)
@@ -130,3 +267,47 @@ func main() {
 		os.Exit(2)
 	}
 }
+
+// normalizePort returns the port as an address to listen on, adding a leading colon if it is missing.
+func normalizePort(port string) string {
+	if !strings.HasPrefix(port, ":") {
+		return ":" + port
+	}
+
+	return port
+}
+
+// serveAdmin serves the /debug/vars endpoint on the port. It runs in the background alongside the API, and a failure
+// to listen is logged without stopping the API.
+func serveAdmin(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	glog.V(10).Infof("Starting admin server on port %s", port)
+
+	err := http.ListenAndServe(port, mux)
+	if err != nil {
+		glog.Errorf("Failed to start admin server: %s", err)
+	}
+}
+
+// runStartupCheck checks that ADP's format still matches what taxcalcd expects and logs a warning for each problem. It
+// runs in the background so that it does not delay serving requests, and its sample calculation uses the request
+// handler's jurisdictions and waits for its rate limit like any other request.
+func runStartupCheck(loader *jurisdiction.Loader, requestHandler *server.RequestHandler) {
+	report := doctor.Run(context.Background(), doctor.Options{
+		Loader:   loader,
+		APIURL:   apiURL,
+		Registry: requestHandler.Registry(),
+		Limiter:  requestHandler.Limiter(),
+	})
+	if report.OK() {
+		glog.Infof("Startup check found no changes to ADP's format in PCC version %s", report.PCCVersion)
+
+		return
+	}
+
+	for _, problem := range report.Problems() {
+		glog.Warningf("Startup check found a change to ADP's format: %s", problem)
+	}
+}
@@ -0,0 +1,28 @@
+// Package cache provides caches for responses from the ADP API. Since ADP may update the tax tables for a year until it
+// is over, each response expires at the start of the year after its tax year, or after the year it was cached in if
+// that is later.
+package cache
+
+import (
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// Cache stores responses from the ADP API by key. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the response for the key and whether it was found. Expired responses are never returned.
+	Get(key string) (*response.Response, bool)
+	// Add stores the response for the key, replacing any existing response. The tax year is the year of the pay date
+	// of the request, which determines when the response expires.
+	Add(key string, taxYear int, resp *response.Response)
+	// Purge removes every response from the cache.
+	Purge()
+}
+
+// ExpiresAt returns when a response for the tax year that was cached at the given time expires, which is the start of
+// the year after the tax year in the same location. Responses for a past tax year expire at the start of the year after
+// the one they were cached in instead, so that they are still cached for a while.
+func ExpiresAt(taxYear int, cachedAt time.Time) time.Time {
+	return time.Date(max(taxYear, cachedAt.Year())+1, time.January, 1, 0, 0, 0, 0, cachedAt.Location())
+}
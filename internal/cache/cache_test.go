@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// newResponse returns a response with the net amount so that responses can be told apart.
+func newResponse(net float64) *response.Response {
+	resp := &response.Response{}
+	resp.Net.Amount = net
+
+	return resp
+}
+
+// fakeClock is a clock for a cache that only changes when set.
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func TestExpiresAt(t *testing.T) {
+	cachedAt := time.Date(2024, time.December, 31, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		taxYear int
+		want    time.Time
+	}{
+		{"current tax year", 2024, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"next tax year", 2025, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"past tax year", 2023, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ExpiresAt(test.taxYear, cachedAt); !got.Equal(test.want) {
+				t.Errorf("ExpiresAt(%d, %s) = %s, want %s", test.taxYear, cachedAt, got, test.want)
+			}
+		})
+	}
+}
+
+// testCache checks the behavior every cache must have, using the clock that the cache was created with.
+func testCache(t *testing.T, cache Cache, clock *fakeClock) {
+	t.Helper()
+
+	if _, ok := cache.Get("missing"); ok {
+		t.Error("Get(missing) found a response, want none")
+	}
+
+	cache.Add("key", 2024, newResponse(1))
+	cache.Add("key", 2024, newResponse(2))
+	cache.Add("other", 2024, newResponse(3))
+	cache.Add("next year", 2025, newResponse(5))
+
+	if resp, ok := cache.Get("key"); !ok || resp.Net.Amount != 2 {
+		t.Errorf("Get(key) = %v, %t, want net 2", resp, ok)
+	}
+
+	clock.now = time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC)
+
+	if _, ok := cache.Get("key"); !ok {
+		t.Error("Get(key) on the last day of the year found no response, want one")
+	}
+
+	clock.now = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if resp, ok := cache.Get("key"); ok {
+		t.Errorf("Get(key) in the next year = %v, want none", resp)
+	}
+
+	if resp, ok := cache.Get("next year"); !ok || resp.Net.Amount != 5 {
+		t.Errorf("Get(next year) in its tax year = %v, %t, want net 5", resp, ok)
+	}
+
+	cache.Add("key", 2025, newResponse(4))
+	cache.Purge()
+
+	if resp, ok := cache.Get("key"); ok {
+		t.Errorf("Get(key) after Purge() = %v, want none", resp)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)}
+
+	memory, err := NewMemory(10)
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+
+	memory.now = clock.Now
+
+	testCache(t, memory, clock)
+}
+
+func TestDisk(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)}
+
+	disk, err := NewDisk(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+
+	disk.now = clock.Now
+
+	testCache(t, disk, clock)
+}
+
+func TestDiskEvictsLeastRecentlyUsed(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)}
+
+	disk, err := NewDisk(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+
+	disk.now = clock.Now
+
+	for _, key := range []string{"first", "second", "third"} {
+		if key == "third" {
+			// Finding the first response marks it as used more recently than the second.
+			disk.Get("first")
+		}
+
+		disk.Add(key, 2024, newResponse(1))
+
+		clock.now = clock.now.Add(time.Minute)
+	}
+
+	for key, want := range map[string]bool{"first": true, "second": false, "third": true} {
+		if _, ok := disk.Get(key); ok != want {
+			t.Errorf("Get(%s) found = %t, want %t", key, ok, want)
+		}
+	}
+}
+
+func TestNewDiskRejectsInvalidSize(t *testing.T) {
+	_, err := NewDisk(t.TempDir(), 0)
+	if err == nil {
+		t.Error("NewDisk() with size 0 error = nil, want an error")
+	}
+}
+
+func TestDiskSurvivesReopening(t *testing.T) {
+	dir := t.TempDir()
+
+	disk, err := NewDisk(dir, 10)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+
+	disk.Add("key with / odd characters", time.Now().Year(), newResponse(7435))
+
+	reopened, err := NewDisk(dir, 10)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+
+	if resp, ok := reopened.Get("key with / odd characters"); !ok || resp.Net.Amount != 7435 {
+		t.Errorf("Get() after reopening = %v, %t, want net 7435", resp, ok)
+	}
+}
+
+func TestNewDiskRemovesExpiredEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	disk, err := NewDisk(dir, 10)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+
+	disk.now = func() time.Time { return time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC) }
+	disk.Add("expired", 2020, newResponse(1))
+
+	err = os.WriteFile(filepath.Join(dir, "corrupt"+diskEntryExtension), []byte("{"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write corrupt entry: %s", err)
+	}
+
+	_, err = NewDisk(dir, 10)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read cache directory: %s", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("cache directory has %d entries after reopening, want 0", len(entries))
+	}
+}
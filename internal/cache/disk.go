@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// diskEntryExtension is the extension of the files that hold the entries of a [Disk] cache.
+const diskEntryExtension = ".json"
+
+// diskEntry is the contents of a file in a [Disk] cache. The key is stored so that it can be checked against the
+// requested key, even though the file name is derived from it.
+type diskEntry struct {
+	Key       string             `json:"key"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+	Response  *response.Response `json:"response"`
+}
+
+// Disk is a cache that stores each response as a JSON file in a directory, so it survives restarts. Once it holds more
+// than its size, the least recently used responses are removed, using the modification times of the files, which are
+// updated whenever a response is added or found. Expired responses are removed when they are looked up and when the
+// cache is opened. Errors reading or writing the files are logged and treated as cache misses. Its zero value is not
+// valid and must be initialized with [NewDisk].
+type Disk struct {
+	dir  string
+	size int
+	now  func() time.Time
+}
+
+var _ Cache = (*Disk)(nil)
+
+// NewDisk opens a cache in the directory that holds up to size responses, creating the directory if it does not exist.
+// Any expired responses in the directory are removed, along with the least recently used ones if there are more than
+// size.
+func NewDisk(dir string, size int) (*Disk, error) {
+	if size <= 0 {
+		return nil, errors.New("cache size must be positive")
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	disk := &Disk{dir: dir, size: size, now: time.Now}
+	disk.removeExpired()
+	disk.evict()
+
+	return disk, nil
+}
+
+// Get implements [Cache].
+func (disk *Disk) Get(key string) (*response.Response, bool) {
+	path := disk.path(key)
+
+	entry, err := readDiskEntry(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false
+	}
+
+	if err != nil {
+		glog.Warningf("Failed to read cache entry %s: %s", path, err)
+
+		return nil, false
+	}
+
+	if entry.Key != key {
+		return nil, false
+	}
+
+	if !disk.now().Before(entry.ExpiresAt) {
+		disk.remove(path)
+
+		return nil, false
+	}
+
+	disk.touch(path)
+
+	return entry.Response, true
+}
+
+// Add implements [Cache]. The entry is written to a temporary file and renamed into place, so a concurrent Get never
+// sees a partially written entry.
+func (disk *Disk) Add(key string, taxYear int, resp *response.Response) {
+	entryJSON, err := json.Marshal(diskEntry{Key: key, ExpiresAt: ExpiresAt(taxYear, disk.now()), Response: resp})
+	if err != nil {
+		glog.Warningf("Failed to encode cache entry for key `%s`: %s", key, err)
+
+		return
+	}
+
+	file, err := os.CreateTemp(disk.dir, "entry-*.tmp")
+	if err != nil {
+		glog.Warningf("Failed to create cache entry for key `%s`: %s", key, err)
+
+		return
+	}
+
+	_, err = file.Write(entryJSON)
+	closeErr := file.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), disk.path(key))
+	}
+
+	if err != nil {
+		glog.Warningf("Failed to write cache entry for key `%s`: %s", key, err)
+
+		disk.remove(file.Name())
+
+		return
+	}
+
+	disk.touch(disk.path(key))
+	disk.evict()
+}
+
+// Purge implements [Cache].
+func (disk *Disk) Purge() {
+	for _, path := range disk.entryPaths() {
+		disk.remove(path)
+	}
+}
+
+// path returns the path of the file for the key. Keys are hashed since they may contain any characters.
+func (disk *Disk) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return filepath.Join(disk.dir, hex.EncodeToString(hash[:])+diskEntryExtension)
+}
+
+// entryPaths returns the paths of every entry in the cache.
+func (disk *Disk) entryPaths() []string {
+	paths, err := filepath.Glob(filepath.Join(disk.dir, "*"+diskEntryExtension))
+	if err != nil {
+		glog.Warningf("Failed to list cache entries: %s", err)
+	}
+
+	return paths
+}
+
+// removeExpired removes every entry that has expired or cannot be read.
+func (disk *Disk) removeExpired() {
+	now := disk.now()
+
+	for _, path := range disk.entryPaths() {
+		entry, err := readDiskEntry(path)
+		if err != nil || !now.Before(entry.ExpiresAt) {
+			glog.V(10).Infof("Removing expired or invalid cache entry %s", path)
+
+			disk.remove(path)
+		}
+	}
+}
+
+// evict removes the least recently used entries until there are at most size left.
+func (disk *Disk) evict() {
+	paths := disk.entryPaths()
+	if len(paths) <= disk.size {
+		return
+	}
+
+	modTimes := make(map[string]time.Time, len(paths))
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+
+	slices.SortFunc(paths, func(a, b string) int {
+		return modTimes[a].Compare(modTimes[b])
+	})
+
+	for _, path := range paths[:len(paths)-disk.size] {
+		glog.V(10).Infof("Evicting least recently used cache entry %s", path)
+
+		disk.remove(path)
+	}
+}
+
+// touch sets the modification time of the file at the path to now, marking the entry as recently used.
+func (disk *Disk) touch(path string) {
+	now := disk.now()
+
+	err := os.Chtimes(path, now, now)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		glog.Warningf("Failed to update cache entry %s: %s", path, err)
+	}
+}
+
+// remove removes the file at the path, logging any error other than it not existing.
+func (disk *Disk) remove(path string) {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		glog.Warningf("Failed to remove cache entry %s: %s", path, err)
+	}
+}
+
+// readDiskEntry reads and decodes the entry in the file at the path.
+func readDiskEntry(path string) (*diskEntry, error) {
+	entryJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &diskEntry{}
+
+	err = json.Unmarshal(entryJSON, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
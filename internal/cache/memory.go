@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"time"
+
+	lruv2 "github.com/hashicorp/golang-lru/v2"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// memoryEntry is a response in a [Memory] cache along with when it expires.
+type memoryEntry struct {
+	response  *response.Response
+	expiresAt time.Time
+}
+
+// Memory is an in-memory cache that evicts the least recently used response once it is full. Its contents are lost
+// when the process exits. Its zero value is not valid and must be initialized with [NewMemory].
+type Memory struct {
+	lru *lruv2.Cache[string, memoryEntry]
+	now func() time.Time
+}
+
+var _ Cache = (*Memory)(nil)
+
+// NewMemory creates a new in-memory cache that holds up to size responses. Each cached response will consume roughly
+// 600 bytes.
+func NewMemory(size int) (*Memory, error) {
+	lru, err := lruv2.New[string, memoryEntry](size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Memory{lru: lru, now: time.Now}, nil
+}
+
+// Get implements [Cache].
+func (memory *Memory) Get(key string) (*response.Response, bool) {
+	entry, ok := memory.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if !memory.now().Before(entry.expiresAt) {
+		memory.lru.Remove(key)
+
+		return nil, false
+	}
+
+	return entry.response, true
+}
+
+// Add implements [Cache].
+func (memory *Memory) Add(key string, taxYear int, resp *response.Response) {
+	memory.lru.Add(key, memoryEntry{response: resp, expiresAt: ExpiresAt(taxYear, memory.now())})
+}
+
+// Purge implements [Cache].
+func (memory *Memory) Purge() {
+	memory.lru.Purge()
+}
@@ -0,0 +1,153 @@
+// Package compare calculates the same salary in several states and ranks them by net income. It is meant for
+// comparing where to live and work.
+package compare
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// AllStates is the keyword that may be given instead of a state code to compare every state.
+const AllStates = "all"
+
+// Result is the calculation for a single state in a comparison. The amounts are per pay period, and the difference is
+// the net income less the net income of the best state, so it is zero for the best state and negative otherwise.
+type Result struct {
+	State      string  `json:"state"`
+	Name       string  `json:"name"`
+	Net        float64 `json:"net"`
+	StateTaxes float64 `json:"stateTaxes"`
+	TotalTaxes float64 `json:"totalTaxes"`
+	Difference float64 `json:"difference"`
+}
+
+// CalculateFunc calculates the response for the salary being compared as if living and working in the state with the
+// given code. It is called for each state in a comparison.
+type CalculateFunc func(ctx context.Context, state string) (*response.Response, error)
+
+// StateCodes normalizes the state codes to upper case and removes duplicates. If any code is [AllStates], the codes of
+// every state in the registry are returned instead, loading the jurisdictions first if they have not been loaded.
+func StateCodes(ctx context.Context, registry *jurisdiction.Registry, codes []string) ([]string, error) {
+	var states []string
+
+	if IncludesAllStates(codes) {
+		return allStateCodes(ctx, registry)
+	}
+
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+
+		if code != "" && !slices.Contains(states, code) {
+			states = append(states, code)
+		}
+	}
+
+	if len(states) == 0 {
+		return nil, fmt.Errorf("at least one state must be specified")
+	}
+
+	return states, nil
+}
+
+// IncludesAllStates reports whether any of the codes is [AllStates], ignoring case and surrounding whitespace.
+func IncludesAllStates(codes []string) bool {
+	return slices.ContainsFunc(codes, func(code string) bool {
+		return strings.EqualFold(strings.TrimSpace(code), AllStates)
+	})
+}
+
+// allStateCodes returns the codes of every state in the registry, sorted, loading the jurisdictions if needed.
+func allStateCodes(ctx context.Context, registry *jurisdiction.Registry) ([]string, error) {
+	if !registry.Loaded() {
+		glog.V(10).Info("Jurisdictions not loaded, loading to compare all states")
+
+		err := registry.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jurisdictions: %w", err)
+		}
+	}
+
+	var states []string
+	for _, state := range registry.States() {
+		states = append(states, state.JurisdictionCode.Code)
+	}
+
+	if len(states) == 0 {
+		return nil, fmt.Errorf("no states have been loaded")
+	}
+
+	return states, nil
+}
+
+// Run calculates every state in order and returns the results sorted from the highest net income to the lowest. Ties
+// are broken by the state code. The names of the states are looked up in the registry. If any calculation fails, the
+// comparison is stopped and the error is returned.
+func Run(
+	ctx context.Context, registry *jurisdiction.Registry, states []string, calculate CalculateFunc,
+) ([]Result, error) {
+	results := make([]Result, 0, len(states))
+
+	for i, state := range states {
+		glog.V(10).Infof("Calculating state %d of %d: %s", i+1, len(states), state)
+
+		resp, err := calculate(ctx, state)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate state %s: %w", state, err)
+		}
+
+		result := Result{
+			State:      state,
+			Net:        resp.Net.Amount,
+			StateTaxes: resp.Taxes.State.SummaryEntity.Amount,
+			TotalTaxes: resp.Taxes.SummaryEntity.Amount,
+		}
+
+		if found, ok := registry.Lookup(state); ok {
+			result.Name = found.JurisdictionCode.Name
+		}
+
+		results = append(results, result)
+	}
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(cmp.Compare(b.Net, a.Net), strings.Compare(a.State, b.State))
+	})
+
+	for i := range results {
+		results[i].Difference = results[i].Net - results[0].Net
+	}
+
+	return results, nil
+}
+
+// WriteCSV writes the results as CSV with a header row.
+func WriteCSV(writer io.Writer, results []Result) error {
+	csvWriter := csv.NewWriter(writer)
+
+	_ = csvWriter.Write([]string{"state", "name", "net", "state_taxes", "total_taxes", "difference"})
+
+	for _, result := range results {
+		_ = csvWriter.Write([]string{
+			result.State,
+			result.Name,
+			strconv.FormatFloat(result.Net, 'f', 2, 64),
+			strconv.FormatFloat(result.StateTaxes, 'f', 2, 64),
+			strconv.FormatFloat(result.TotalTaxes, 'f', 2, 64),
+			strconv.FormatFloat(result.Difference, 'f', 2, 64),
+		})
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
@@ -0,0 +1,101 @@
+package compare
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// loadFakeJurisdictions returns a registry with the jurisdictions loaded from a fake ADP server.
+func loadFakeJurisdictions(t *testing.T) *jurisdiction.Registry {
+	t.Helper()
+
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	registry := jurisdiction.NewRegistry(&jurisdiction.Loader{BaseURL: server.URL})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load jurisdictions from fake server: %s", err)
+	}
+
+	return registry
+}
+
+func TestStateCodes(t *testing.T) {
+	registry := loadFakeJurisdictions(t)
+
+	got, err := StateCodes(context.Background(), registry, []string{"ca", " TX", "", "CA", "ny"})
+	if err != nil {
+		t.Fatalf("StateCodes() error = %v", err)
+	}
+
+	if want := []string{"CA", "TX", "NY"}; !slices.Equal(got, want) {
+		t.Errorf("StateCodes() = %v, want %v", got, want)
+	}
+
+	got, err = StateCodes(context.Background(), registry, []string{"CA", "All"})
+	if err != nil {
+		t.Fatalf("StateCodes(all) error = %v", err)
+	}
+
+	if len(got) != len(fakeadp.States) || !slices.IsSorted(got) {
+		t.Errorf("StateCodes(all) = %v, want all %d states sorted", got, len(fakeadp.States))
+	}
+
+	_, err = StateCodes(context.Background(), registry, []string{""})
+	if err == nil {
+		t.Error("StateCodes(empty) error = nil, want an error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	registry := loadFakeJurisdictions(t)
+
+	stateTaxes := map[string]float64{"CA": 800, "TX": 0, "WA": 0, "NY": 600}
+
+	results, err := Run(context.Background(), registry, []string{"CA", "WA", "NY", "TX"},
+		func(_ context.Context, state string) (*response.Response, error) {
+			resp := &response.Response{}
+			resp.Taxes.State.SummaryEntity.Amount = stateTaxes[state]
+			resp.Taxes.SummaryEntity.Amount = 1000 + stateTaxes[state]
+			resp.Net.Amount = 9000 - stateTaxes[state]
+
+			return resp, nil
+		})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	wantStates := []string{"TX", "WA", "NY", "CA"}
+	wantDifferences := []float64{0, 0, -600, -800}
+
+	for i, result := range results {
+		if result.State != wantStates[i] || result.Difference != wantDifferences[i] {
+			t.Errorf("result %d = %s with difference %.2f, want %s with difference %.2f",
+				i, result.State, result.Difference, wantStates[i], wantDifferences[i])
+		}
+
+		if result.Name == "" {
+			t.Errorf("result %d has no name", i)
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	_, err := Run(context.Background(), &jurisdiction.Registry{}, []string{"CA"},
+		func(context.Context, string) (*response.Response, error) {
+			return nil, errFailed
+		})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Run() error = %v, want %v", err, errFailed)
+	}
+}
@@ -0,0 +1,234 @@
+// Package doctor checks that ADP's format still matches what the tax calculator expects. The jurisdictions are scraped
+// out of JS with regular expressions and responses are decoded without failing on unknown fields, so a change on ADP's
+// side would otherwise go unnoticed until calculations silently became wrong.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/request"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+const (
+	// sampleSalary is the annual salary of the sample calculation.
+	sampleSalary = 60000
+	// sampleStateCode is the state of the sample calculation. It has an income tax so that state taxes are included in
+	// the response.
+	sampleStateCode = "CA"
+)
+
+// Options configures where the checks are run. The zero value checks the real ADP.
+type Options struct {
+	// Loader loads the jurisdictions from ADP. If nil, the zero [jurisdiction.Loader] is used.
+	Loader *jurisdiction.Loader
+	// APIURL is the URL of the ADP API that the sample calculation is sent to. If empty, [request.APIURL] is used.
+	APIURL string
+	// Registry is where the sample calculation looks up its jurisdictions, loading them if needed. If nil, the
+	// jurisdictions scraped by the loader are used.
+	Registry *jurisdiction.Registry
+	// Limiter rate limits the sample calculation along with any other requests to the ADP API. If nil, it is not rate
+	// limited.
+	Limiter request.Limiter
+}
+
+// Check is the result of a single check.
+type Check struct {
+	// Name describes what was checked.
+	Name string
+	// Err is set if the check could not be run, such as when ADP could not be reached.
+	Err error
+	// Problems describes each way that ADP's format differs from what is expected.
+	Problems []string
+}
+
+// OK returns whether the check ran and found no problems.
+func (check *Check) OK() bool {
+	return check.Err == nil && len(check.Problems) == 0
+}
+
+// Report is the result of every check along with the PCC version that they were run against.
+type Report struct {
+	PCCVersion string
+	Checks     []*Check
+}
+
+// OK returns whether every check ran and found no problems.
+func (report *Report) OK() bool {
+	for _, check := range report.Checks {
+		if !check.OK() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Problems returns every problem found by the checks, prefixed with the name of the check that found it. Checks that
+// could not be run are included as well.
+func (report *Report) Problems() []string {
+	var problems []string
+
+	for _, check := range report.Checks {
+		if check.Err != nil {
+			problems = append(problems, fmt.Sprintf("%s: failed to run: %s", check.Name, check.Err))
+		}
+
+		for _, problem := range check.Problems {
+			problems = append(problems, fmt.Sprintf("%s: %s", check.Name, problem))
+		}
+	}
+
+	return problems
+}
+
+// Write writes the report to the writer in a human readable format, with each check on its own line followed by any
+// problems it found.
+func (report *Report) Write(writer io.Writer) error {
+	pccVersion := report.PCCVersion
+	if pccVersion == "" {
+		pccVersion = "unknown"
+	}
+
+	_, err := fmt.Fprintf(writer, "PCC version %s\n\n", pccVersion)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range report.Checks {
+		status := "ok"
+
+		switch {
+		case check.Err != nil:
+			status = fmt.Sprintf("failed to run: %s", check.Err)
+		case len(check.Problems) == 1:
+			status = "1 problem"
+		case len(check.Problems) > 1:
+			status = fmt.Sprintf("%d problems", len(check.Problems))
+		}
+
+		_, err = fmt.Fprintf(writer, "%s: %s\n", check.Name, status)
+		if err != nil {
+			return err
+		}
+
+		for _, problem := range check.Problems {
+			_, err = fmt.Fprintf(writer, "  - %s\n", problem)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Run checks the jurisdictions scraped from ADP with [jurisdiction.Loader.Diagnose] and sends a sample calculation
+// using them, or the ones in the registry if it is set, to check that the response decodes without unknown fields.
+func Run(ctx context.Context, options Options) *Report {
+	loader := options.Loader
+	if loader == nil {
+		loader = &jurisdiction.Loader{}
+	}
+
+	apiURL := options.APIURL
+	if apiURL == "" {
+		apiURL = request.APIURL
+	}
+
+	report := &Report{}
+
+	jurisdictionsCheck := &Check{Name: "jurisdictions"}
+	report.Checks = append(report.Checks, jurisdictionsCheck)
+
+	snapshot := &jurisdiction.Snapshot{}
+
+	diagnosis, err := loader.Diagnose(ctx)
+	if err != nil {
+		glog.V(10).Infof("Failed to diagnose jurisdictions: %s", err)
+
+		jurisdictionsCheck.Err = err
+	} else {
+		snapshot = diagnosis.Snapshot
+		report.PCCVersion = snapshot.PCCVersion
+		jurisdictionsCheck.Problems = diagnosis.Problems
+	}
+
+	registry := options.Registry
+	if registry == nil {
+		registry = jurisdiction.NewRegistryFromSnapshot(snapshot)
+	} else if err := registry.Load(ctx); err != nil {
+		glog.V(10).Infof("Failed to load jurisdictions for the sample calculation: %s", err)
+	}
+
+	report.Checks = append(report.Checks, checkCalculation(ctx, apiURL, registry, options.Limiter))
+
+	return report
+}
+
+// checkCalculation sends a sample calculation to the ADP API, waiting for the limiter if there is one, and checks the
+// response. The sample state is only included if it is in the registry, so that the calculation can still be checked
+// when scraping the jurisdictions failed.
+func checkCalculation(
+	ctx context.Context, apiURL string, registry *jurisdiction.Registry, limiter request.Limiter,
+) *Check {
+	check := &Check{Name: "calculation"}
+
+	builder := request.NewBuilder(apiURL).WithRegistry(registry).WithLimiter(limiter).
+		WithSalary(sampleSalary, request.AnnualSalaryFrequency)
+
+	_, hasState := registry.Lookup(sampleStateCode)
+	if hasState {
+		builder.WithJurisdictionsByCode(ctx, sampleStateCode)
+	}
+
+	body, err := builder.SendRawContext(ctx)
+	if err != nil {
+		glog.V(10).Infof("Failed to send sample calculation: %s", err)
+
+		check.Err = err
+
+		return check
+	}
+
+	unknownFields, err := response.UnknownFields(body)
+	if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("response is not a JSON object: %s", err))
+
+		return check
+	}
+
+	for _, field := range unknownFields {
+		check.Problems = append(check.Problems, fmt.Sprintf("response has unknown field %s", field))
+	}
+
+	decoded := &response.Response{}
+
+	err = json.Unmarshal(body, decoded)
+	if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("response no longer decodes: %s", err))
+
+		return check
+	}
+
+	if decoded.Gross.Amount <= 0 || decoded.Net.Amount <= 0 {
+		check.Problems = append(check.Problems, fmt.Sprintf(
+			"response has gross income %.2f and net income %.2f, want both to be positive",
+			decoded.Gross.Amount, decoded.Net.Amount))
+	}
+
+	if len(decoded.Taxes.Federal.Entities) == 0 {
+		check.Problems = append(check.Problems, "response has no federal taxes")
+	}
+
+	if hasState && len(decoded.Taxes.State.Entities) == 0 {
+		check.Problems = append(check.Problems, "response has no state taxes for "+sampleStateCode)
+	}
+
+	return check
+}
@@ -0,0 +1,140 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+)
+
+func TestRun(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	report := Run(context.Background(), Options{
+		Loader: &jurisdiction.Loader{BaseURL: server.URL},
+		APIURL: server.APIURL(),
+	})
+
+	if !report.OK() {
+		t.Errorf("OK() = false with problems %q", report.Problems())
+	}
+
+	if report.PCCVersion != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion = %q, want %q", report.PCCVersion, fakeadp.PCCVersion)
+	}
+
+	var written bytes.Buffer
+
+	err := report.Write(&written)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := "PCC version " + fakeadp.PCCVersion + "\n\njurisdictions: ok\ncalculation: ok\n"
+	if written.String() != want {
+		t.Errorf("Write() = %q, want %q", written.String(), want)
+	}
+}
+
+// countingLimiter is a [request.Limiter] that never waits and counts how many times it was waited on.
+type countingLimiter struct {
+	waits int
+}
+
+func (limiter *countingLimiter) Wait(context.Context) error {
+	limiter.waits++
+
+	return nil
+}
+
+func TestRunUsesRegistryAndLimiter(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	loader := &jurisdiction.Loader{BaseURL: server.URL}
+	registry := jurisdiction.NewRegistry(loader)
+	limiter := &countingLimiter{}
+
+	report := Run(context.Background(), Options{
+		Loader:   loader,
+		APIURL:   server.APIURL(),
+		Registry: registry,
+		Limiter:  limiter,
+	})
+
+	if !report.OK() {
+		t.Errorf("OK() = false with problems %q", report.Problems())
+	}
+
+	if !registry.Loaded() {
+		t.Error("registry was not loaded for the sample calculation")
+	}
+
+	if limiter.waits != 1 {
+		t.Errorf("limiter was waited on %d times, want 1", limiter.waits)
+	}
+}
+
+func TestRunReportsChangedResponse(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		resp.Header().Set("Content-Type", "application/json")
+		_, _ = resp.Write([]byte(`{"gross":{"amount":5000},"net":{"amount":"4000"},"employer":{"name":"ADP"}}`))
+	}))
+	defer api.Close()
+
+	report := Run(context.Background(), Options{Loader: &jurisdiction.Loader{BaseURL: server.URL}, APIURL: api.URL})
+
+	problems := report.Problems()
+	want := []string{
+		"calculation: response has unknown field employer",
+		"calculation: response no longer decodes: ",
+	}
+
+	if len(problems) != len(want) {
+		t.Fatalf("Problems() = %q, want %d problems", problems, len(want))
+	}
+
+	for i, problem := range problems {
+		if !strings.HasPrefix(problem, want[i]) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problem, want[i])
+		}
+	}
+}
+
+func TestRunUnreachable(t *testing.T) {
+	server := fakeadp.NewServer()
+	server.Close()
+
+	report := Run(context.Background(), Options{
+		Loader: &jurisdiction.Loader{BaseURL: server.URL},
+		APIURL: server.APIURL(),
+	})
+
+	if report.OK() {
+		t.Fatal("OK() = true when ADP is unreachable")
+	}
+
+	var names []string
+
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+
+		if check.Err == nil {
+			t.Errorf("check %s has no error when ADP is unreachable", check.Name)
+		}
+	}
+
+	if want := []string{"jurisdictions", "calculation"}; !slices.Equal(names, want) {
+		t.Errorf("checks = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,470 @@
+package fakeadp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+const (
+	socialSecurityRate = 0.062
+	medicareRate       = 0.0145
+	// supplementalRate is the federal income tax rate for supplemental pay with the flat withholding method.
+	supplementalRate = 0.22
+	currencyCode     = "USD"
+)
+
+// periodsPerYear maps the pay frequency codes accepted by the fake server to the number of pay periods in a year.
+var periodsPerYear = map[string]float64{
+	"DAILY":        260,
+	"WEEKLY":       52,
+	"BI_WEEKLY":    26,
+	"SEMI_MONTHLY": 24,
+	"MONTHLY":      12,
+	"QUARTERLY":    4,
+	"SEMI_ANNUAL":  2,
+	"ANNUAL":       1,
+}
+
+// calculationRequest is the subset of the calculation request that the fake server uses.
+type calculationRequest struct {
+	StatutoryPolicyInputs []struct {
+		ID    string `json:"id"`
+		Value any    `json:"value"`
+	} `json:"statutoryPolicyInputs"`
+	Jurisdictions struct {
+		WorkedInJurisdictions []jurisdictionJSON `json:"workedInJurisdictions"`
+		LivedInJurisdictions  []jurisdictionJSON `json:"livedInJurisdictions"`
+	} `json:"jurisdictions"`
+	PayFrequencyCode struct {
+		Code string `json:"code"`
+	} `json:"payFrequencyCode"`
+	BusinessPolicies []struct {
+		Alias  string `json:"alias"`
+		Label  string `json:"label"`
+		Inputs []struct {
+			Name  string  `json:"name"`
+			Value float64 `json:"value"`
+		} `json:"inputs"`
+	} `json:"businessPolicies"`
+	AdditionalEarnings struct {
+		PayLines []struct {
+			Unit struct {
+				Value string `json:"value"`
+			} `json:"unit"`
+			Amount struct {
+				Value float64 `json:"value"`
+			} `json:"amount"`
+			Name struct {
+				Value string `json:"value"`
+			} `json:"name"`
+			ClientFactor struct {
+				Value float64 `json:"value"`
+			} `json:"clientFactor"`
+			WithholdingMethod *struct {
+				Code string `json:"code"`
+			} `json:"supplementalWithholdingMethod"`
+		} `json:"payLines"`
+	} `json:"additionalEarnings"`
+	Deductions []struct {
+		DeductionType struct {
+			Label  string `json:"label"`
+			PreTax bool   `json:"preTax"`
+		} `json:"deductionType"`
+		AmountType struct {
+			Code string `json:"code"`
+		} `json:"amountType"`
+		Value float64 `json:"value"`
+	} `json:"deductions"`
+}
+
+type jurisdictionJSON struct {
+	JurisdictionID   string `json:"jurisdictionID"`
+	JurisdictionCode struct {
+		Name string `json:"name"`
+		Code string `json:"code"`
+	} `json:"jurisdictionCode"`
+	JurisdictionLevelCode struct {
+		Code string `json:"code"`
+	} `json:"jurisdictionLevelCode"`
+}
+
+type summaryJSON struct {
+	Amount       float64 `json:"amount"`
+	CurrencyCode string  `json:"currencyCode"`
+	Label        string  `json:"label"`
+}
+
+type earningJSON struct {
+	Amount       float64 `json:"amount"`
+	CurrencyCode string  `json:"currencyCode"`
+	Label        string  `json:"label"`
+	Hours        float64 `json:"hours"`
+}
+
+type taxJSON struct {
+	Amount             float64          `json:"amount"`
+	CurrencyCode       string           `json:"currencyCode"`
+	Label              string           `json:"label"`
+	Jurisdiction       jurisdictionJSON `json:"jurisdiction"`
+	ParentJurisdiction jurisdictionJSON `json:"parentJurisdiction"`
+}
+
+type taxesJSON struct {
+	Entities      []taxJSON   `json:"entities"`
+	SummaryEntity summaryJSON `json:"summaryEntity"`
+}
+
+type deductionJSON struct {
+	Amount       float64 `json:"amount"`
+	CurrencyCode string  `json:"currencyCode"`
+	Label        string  `json:"label"`
+	PreTax       bool    `json:"preTax"`
+}
+
+// calculationResponse is the response of the calculations API.
+type calculationResponse struct {
+	Earnings struct {
+		Entities      []earningJSON `json:"entities"`
+		SummaryEntity summaryJSON   `json:"summaryEntity"`
+	} `json:"earnings"`
+	Taxes struct {
+		Federal       taxesJSON   `json:"federal"`
+		State         taxesJSON   `json:"state"`
+		Local         taxesJSON   `json:"local"`
+		Territory     taxesJSON   `json:"territory"`
+		SummaryEntity summaryJSON `json:"summaryEntity"`
+	} `json:"taxes"`
+	Gross      summaryJSON `json:"gross"`
+	Net        summaryJSON `json:"net"`
+	Deductions struct {
+		Entities      []deductionJSON `json:"entities"`
+		SummaryEntity summaryJSON     `json:"summaryEntity"`
+	} `json:"deductions"`
+}
+
+func (server *Server) handleCalculation(resp http.ResponseWriter, req *http.Request) {
+	server.mu.Lock()
+	server.calculationCount++
+	server.mu.Unlock()
+
+	if server.fail(resp) {
+		return
+	}
+
+	calcRequest := &calculationRequest{}
+
+	err := json.NewDecoder(req.Body).Decode(calcRequest)
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	calcResponse, err := calculate(calcRequest)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(resp).Encode(calcResponse)
+}
+
+// calculate computes the response to a calculation request using flat rates for every jurisdiction.
+func calculate(calcRequest *calculationRequest) (*calculationResponse, error) {
+	periods, ok := periodsPerYear[calcRequest.PayFrequencyCode.Code]
+	if !ok {
+		return nil, fmt.Errorf("invalid pay frequency: %s", calcRequest.PayFrequencyCode.Code)
+	}
+
+	calcResponse := &calculationResponse{}
+
+	gross, flatSupplemental, err := calculateEarnings(calcRequest, calcResponse, periods)
+	if err != nil {
+		return nil, err
+	}
+
+	preTax, postTax := calculateDeductions(calcRequest, calcResponse, gross)
+	taxable := math.Max(gross-preTax, 0)
+
+	calculateFederalTaxes(calcRequest, calcResponse, taxable, flatSupplemental, periods)
+	calculateStateAndLocalTaxes(calcRequest, calcResponse, taxable)
+
+	totalTax := calcResponse.Taxes.Federal.SummaryEntity.Amount + calcResponse.Taxes.State.SummaryEntity.Amount +
+		calcResponse.Taxes.Local.SummaryEntity.Amount
+	calcResponse.Taxes.SummaryEntity = newSummary(totalTax, "Taxes")
+	calcResponse.Gross = newSummary(gross, "Gross pay")
+	calcResponse.Net = newSummary(gross-totalTax-preTax-postTax, "Net pay")
+
+	return calcResponse, nil
+}
+
+// calculateEarnings adds the earnings from the business policies and pay lines to the response and returns the gross
+// pay for the period along with the part of it that is supplemental pay withheld at the flat rate.
+func calculateEarnings(calcRequest *calculationRequest, calcResponse *calculationResponse, periods float64) (
+	float64, float64, error,
+) {
+	if len(calcRequest.BusinessPolicies) < 1 && len(calcRequest.AdditionalEarnings.PayLines) < 1 {
+		return 0, 0, fmt.Errorf("at least one business policy or pay line is required")
+	}
+
+	policyGross, err := calculatePolicyEarnings(calcRequest, calcResponse, periods)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	payLineGross, flatSupplemental, err := calculatePayLineEarnings(calcRequest, calcResponse)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	gross := policyGross + payLineGross
+	calcResponse.Earnings.SummaryEntity = newSummary(gross, "Earnings")
+
+	return gross, flatSupplemental, nil
+}
+
+// calculatePolicyEarnings adds an earning for each business policy to the response and returns their total. An annual
+// salary is split evenly over the periods.
+func calculatePolicyEarnings(calcRequest *calculationRequest, calcResponse *calculationResponse, periods float64) (
+	float64, error,
+) {
+	gross := 0.0
+
+	for _, policy := range calcRequest.BusinessPolicies {
+		inputs := map[string]float64{}
+		for _, input := range policy.Inputs {
+			inputs[input.Name] = input.Value
+		}
+
+		earning := earningJSON{CurrencyCode: currencyCode}
+
+		switch policy.Alias {
+		case "salary":
+			earning.Label, earning.Amount = "Salary", inputs["appliedPayPeriodAmount"]/periods
+		case "salary_per_period":
+			earning.Label, earning.Amount = "Salary", inputs["appliedPayPeriodAmount"]
+		case "hourly":
+			earning.Label, earning.Hours = "Regular", inputs["regularHoursWorked"]
+			earning.Amount = inputs["appliedHourlyRate"] * earning.Hours
+		default:
+			return 0, fmt.Errorf("invalid business policy alias: %s", policy.Alias)
+		}
+
+		earning.Amount = round(earning.Amount)
+		gross += earning.Amount
+		calcResponse.Earnings.Entities = append(calcResponse.Earnings.Entities, earning)
+	}
+
+	return gross, nil
+}
+
+// calculatePayLineEarnings adds an earning for each additional pay line to the response and returns their total along
+// with the part of it that is supplemental pay withheld at the flat rate.
+func calculatePayLineEarnings(calcRequest *calculationRequest, calcResponse *calculationResponse) (
+	float64, float64, error,
+) {
+	gross, flatSupplemental := 0.0, 0.0
+
+	for _, payLine := range calcRequest.AdditionalEarnings.PayLines {
+		units, err := strconv.ParseFloat(payLine.Unit.Value, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid pay line unit: %s", payLine.Unit.Value)
+		}
+
+		factor := payLine.ClientFactor.Value
+		if factor == 0 {
+			factor = 1
+		}
+
+		earning := earningJSON{
+			Amount:       round(units * payLine.Amount.Value * factor),
+			CurrencyCode: currencyCode,
+			Label:        payLine.Name.Value,
+			Hours:        units,
+		}
+
+		gross += earning.Amount
+		calcResponse.Earnings.Entities = append(calcResponse.Earnings.Entities, earning)
+
+		if payLine.WithholdingMethod != nil && payLine.WithholdingMethod.Code == "FLAT" {
+			flatSupplemental += earning.Amount
+		}
+	}
+
+	return gross, flatSupplemental, nil
+}
+
+// calculateDeductions adds the deductions to the response and returns the total pre-tax and post-tax deductions.
+func calculateDeductions(calcRequest *calculationRequest, calcResponse *calculationResponse, gross float64) (
+	float64, float64,
+) {
+	preTax, postTax := 0.0, 0.0
+
+	for _, deduction := range calcRequest.Deductions {
+		amount := deduction.Value
+		if deduction.AmountType.Code == "PERCENT" {
+			amount = gross * deduction.Value / 100
+		}
+
+		amount = round(amount)
+
+		if deduction.DeductionType.PreTax {
+			preTax += amount
+		} else {
+			postTax += amount
+		}
+
+		calcResponse.Deductions.Entities = append(calcResponse.Deductions.Entities, deductionJSON{
+			Amount:       amount,
+			CurrencyCode: currencyCode,
+			Label:        deduction.DeductionType.Label,
+			PreTax:       deduction.DeductionType.PreTax,
+		})
+	}
+
+	calcResponse.Deductions.SummaryEntity = newSummary(preTax+postTax, "Deductions")
+
+	return preTax, postTax
+}
+
+// calculateFederalTaxes adds the federal income tax, social security, and Medicare to the response. The federal income
+// tax rate depends on the filing status and multiple jobs checkbox, and the other W4 inputs adjust the annual taxable
+// income and the withholding as they would on the real form. Supplemental pay with the flat withholding method is
+// excluded from that and withheld at the flat supplemental rate instead.
+func calculateFederalTaxes(
+	calcRequest *calculationRequest, calcResponse *calculationResponse, taxable, flatSupplemental, periods float64,
+) {
+	inputs := map[string]any{}
+	for _, input := range calcRequest.StatutoryPolicyInputs {
+		inputs[input.ID] = input.Value
+	}
+
+	rate := Federal.Rate
+
+	switch inputs["filingStatus"] {
+	case "MARRIED_FILING_JOINTLY":
+		rate = 0.08
+	case "HEAD_OF_HOUSEHOLD":
+		rate = 0.09
+	}
+
+	if inputs["multipleJobsIndicator"] == true {
+		rate += 0.02
+	}
+
+	regular := math.Max(taxable-flatSupplemental, 0)
+	annualTaxable := regular*periods + floatInput(inputs, "otherIncomeAmount") - floatInput(inputs, "deductionsAmount")
+	annualTax := math.Max(annualTaxable*rate-floatInput(inputs, "dependentsAmount"), 0)
+	incomeTax := annualTax/periods + floatInput(inputs, "additionalTaxAmountPerPayPeriod") +
+		flatSupplemental*supplementalRate
+
+	federal := newJurisdictionJSON(Federal, "FEDERAL")
+	entities := []taxJSON{
+		{Amount: round(incomeTax), Label: "Federal Income Tax", Jurisdiction: federal},
+		{Amount: round(taxable * socialSecurityRate), Label: "Social Security", Jurisdiction: federal},
+		{Amount: round(taxable * medicareRate), Label: "Medicare", Jurisdiction: federal},
+	}
+
+	calcResponse.Taxes.Federal = newTaxes(entities, "Federal taxes")
+}
+
+// calculateStateAndLocalTaxes adds the income tax for every state and local jurisdiction that is lived in or worked in
+// to the response. Each jurisdiction is only taxed once, even if it is both lived in and worked in.
+func calculateStateAndLocalTaxes(calcRequest *calculationRequest, calcResponse *calculationResponse, taxable float64) {
+	var stateEntities, localEntities []taxJSON
+
+	seen := map[string]bool{}
+	requested := slices.Concat(calcRequest.Jurisdictions.LivedInJurisdictions,
+		calcRequest.Jurisdictions.WorkedInJurisdictions)
+
+	for _, requestedJurisdiction := range requested {
+		if seen[requestedJurisdiction.JurisdictionID] {
+			continue
+		}
+
+		seen[requestedJurisdiction.JurisdictionID] = true
+
+		jurisdiction, ok := jurisdictionByID(requestedJurisdiction.JurisdictionID)
+		if !ok || jurisdiction.ID == Federal.ID {
+			continue
+		}
+
+		if jurisdiction.ParentCode == "" {
+			stateEntities = append(stateEntities, taxJSON{
+				Amount:       round(taxable * jurisdiction.Rate),
+				Label:        jurisdiction.Name + " Income Tax",
+				Jurisdiction: newJurisdictionJSON(jurisdiction, "STATE"),
+			})
+
+			continue
+		}
+
+		localEntities = append(localEntities, taxJSON{
+			Amount:             round(taxable * jurisdiction.Rate),
+			Label:              jurisdiction.Name + " Income Tax",
+			Jurisdiction:       newJurisdictionJSON(jurisdiction, "LOCAL"),
+			ParentJurisdiction: newJurisdictionJSON(stateByCode(jurisdiction.ParentCode), "STATE"),
+		})
+	}
+
+	calcResponse.Taxes.State = newTaxes(stateEntities, "State taxes")
+	calcResponse.Taxes.Local = newTaxes(localEntities, "Local taxes")
+	calcResponse.Taxes.Territory = newTaxes(nil, "Territory taxes")
+}
+
+func stateByCode(code string) Jurisdiction {
+	for _, state := range States {
+		if state.Code == code {
+			return state
+		}
+	}
+
+	return Jurisdiction{}
+}
+
+func newJurisdictionJSON(jurisdiction Jurisdiction, level string) jurisdictionJSON {
+	encoded := jurisdictionJSON{JurisdictionID: jurisdiction.ID}
+	encoded.JurisdictionCode.Name = jurisdiction.Name
+	encoded.JurisdictionCode.Code = jurisdiction.Code
+	encoded.JurisdictionLevelCode.Code = level
+
+	return encoded
+}
+
+func newTaxes(entities []taxJSON, label string) taxesJSON {
+	total := 0.0
+
+	for i := range entities {
+		entities[i].CurrencyCode = currencyCode
+		total += entities[i].Amount
+	}
+
+	if entities == nil {
+		entities = []taxJSON{}
+	}
+
+	return taxesJSON{Entities: entities, SummaryEntity: newSummary(total, label)}
+}
+
+func newSummary(amount float64, label string) summaryJSON {
+	return summaryJSON{Amount: round(amount), CurrencyCode: currencyCode, Label: label}
+}
+
+// floatInput returns the statutory policy input with the ID as a float, or zero if it is missing or not a number.
+func floatInput(inputs map[string]any, id string) float64 {
+	value, _ := inputs[id].(float64)
+
+	return value
+}
+
+// round rounds the amount to the nearest cent.
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,125 @@
+// Package fakeadp implements a stand-in for the ADP endpoints used by the tax calculator. It serves the PWC loader, the
+// dynamic control generator bundle that jurisdictions are scraped from, and the calculations API. Calculations use
+// simple flat rates so that responses are deterministic and easy to check by hand. They are not real tax calculations.
+package fakeadp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+const (
+	// PCCVersion is the version of the PCC that the fake loader advertises.
+	PCCVersion = "2024.24.0"
+
+	// LoaderPath is the path of the PWC loader script.
+	LoaderPath = "/pwc/dist/loader.js"
+	// CalculationsPath is the path of the calculations API.
+	CalculationsPath = "/api/pcc/v2/calculations"
+)
+
+// Server is a fake ADP server. Its zero value is not valid and it must be created with [NewServer]. It must be closed
+// once it is no longer needed.
+type Server struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	pccVersion       string
+	failures         []int
+	calculationCount int
+}
+
+// NewServer starts a new fake ADP server listening on a local port.
+func NewServer() *Server {
+	server := &Server{pccVersion: PCCVersion}
+	server.Server = httptest.NewServer(server.Handler())
+
+	return server
+}
+
+// Handler returns the handler that serves the fake ADP endpoints. It can be used to serve the fake endpoints from a
+// server other than the one started by [NewServer].
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+LoaderPath, server.handleLoader)
+	mux.HandleFunc("GET /pwc/dist/pcc/{version}/esm/pwc-dynamic-control-generator_20.entry.js", server.handleDynamic)
+	mux.HandleFunc("POST "+CalculationsPath, server.handleCalculation)
+
+	return mux
+}
+
+// APIURL returns the URL of the calculations API on the server.
+func (server *Server) APIURL() string {
+	return server.URL + CalculationsPath
+}
+
+// SetPCCVersion changes the PCC version that the loader advertises. The dynamic bundle is served for any version.
+func (server *Server) SetPCCVersion(version string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.pccVersion = version
+}
+
+// FailNext makes the next calls to any endpoint respond with the given status codes in order instead of their usual
+// response. A status of 429 Too Many Requests includes a Retry-After header of zero seconds.
+func (server *Server) FailNext(statuses ...int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.failures = append(server.failures, statuses...)
+}
+
+// CalculationCount returns the number of requests the calculations API has received, including failed ones.
+func (server *Server) CalculationCount() int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return server.calculationCount
+}
+
+// fail responds with the next failure status if there is one and returns whether it did.
+func (server *Server) fail(resp http.ResponseWriter) bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if len(server.failures) < 1 {
+		return false
+	}
+
+	status := server.failures[0]
+	server.failures = server.failures[1:]
+
+	if status == http.StatusTooManyRequests {
+		resp.Header().Set("Retry-After", "0")
+	}
+
+	http.Error(resp, http.StatusText(status), status)
+
+	return true
+}
+
+func (server *Server) handleLoader(resp http.ResponseWriter, _ *http.Request) {
+	if server.fail(resp) {
+		return
+	}
+
+	server.mu.Lock()
+	version := server.pccVersion
+	server.mu.Unlock()
+
+	resp.Header().Set("Content-Type", "text/javascript")
+	fmt.Fprintf(resp, loaderTemplate, version)
+}
+
+func (server *Server) handleDynamic(resp http.ResponseWriter, _ *http.Request) {
+	if server.fail(resp) {
+		return
+	}
+
+	resp.Header().Set("Content-Type", "text/javascript")
+	fmt.Fprint(resp, dynamicBundle())
+}
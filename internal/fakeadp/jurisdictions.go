@@ -0,0 +1,154 @@
+package fakeadp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Jurisdiction is a jurisdiction known to the fake server along with the flat income tax rate it applies. The parent
+// code is only set for local jurisdictions.
+type Jurisdiction struct {
+	ID         string
+	Code       string
+	Name       string
+	ParentCode string
+	Rate       float64
+}
+
+const (
+	defaultStateRate = 0.05
+	defaultLocalRate = 0.02
+)
+
+// Federal is the federal jurisdiction served by the fake server. Its rate is for the federal income tax only. Social
+// security and Medicare are always withheld in addition to it.
+var Federal = Jurisdiction{
+	ID:   "dea07e6d-9432-4f65-958b-25f09e18117e",
+	Code: "US",
+	Name: "United States Federal",
+	Rate: 0.10,
+}
+
+// States are the state jurisdictions served by the fake server, including the District of Columbia. States without an
+// income tax have a rate of zero and the rest use a flat rate, with a few exceptions to make comparisons interesting.
+var States = newStates()
+
+// Locals are the local jurisdictions served by the fake server.
+var Locals = []Jurisdiction{
+	{ID: fakeID(101), Code: "NYC", Name: "New York City", ParentCode: "NY", Rate: 0.03},
+	{ID: fakeID(102), Code: "YON", Name: "Yonkers", ParentCode: "NY", Rate: defaultLocalRate},
+	{ID: fakeID(103), Code: "PHL", Name: "Philadelphia", ParentCode: "PA", Rate: 0.0375},
+	{ID: fakeID(104), Code: "CSD", Name: "Columbus City School District", ParentCode: "OH", Rate: defaultLocalRate},
+}
+
+var stateNames = [][2]string{
+	{"AK", "Alaska"}, {"AL", "Alabama"}, {"AR", "Arkansas"}, {"AZ", "Arizona"}, {"CA", "California"},
+	{"CO", "Colorado"}, {"CT", "Connecticut"}, {"DC", "District of Columbia"}, {"DE", "Delaware"}, {"FL", "Florida"},
+	{"GA", "Georgia"}, {"HI", "Hawaii"}, {"IA", "Iowa"}, {"ID", "Idaho"}, {"IL", "Illinois"}, {"IN", "Indiana"},
+	{"KS", "Kansas"}, {"KY", "Kentucky"}, {"LA", "Louisiana"}, {"MA", "Massachusetts"}, {"MD", "Maryland"},
+	{"ME", "Maine"}, {"MI", "Michigan"}, {"MN", "Minnesota"}, {"MO", "Missouri"}, {"MS", "Mississippi"},
+	{"MT", "Montana"}, {"NC", "North Carolina"}, {"ND", "North Dakota"}, {"NE", "Nebraska"}, {"NH", "New Hampshire"},
+	{"NJ", "New Jersey"}, {"NM", "New Mexico"}, {"NV", "Nevada"}, {"NY", "New York"}, {"OH", "Ohio"},
+	{"OK", "Oklahoma"}, {"OR", "Oregon"}, {"PA", "Pennsylvania"}, {"RI", "Rhode Island"}, {"SC", "South Carolina"},
+	{"SD", "South Dakota"}, {"TN", "Tennessee"}, {"TX", "Texas"}, {"UT", "Utah"}, {"VA", "Virginia"},
+	{"VT", "Vermont"}, {"WA", "Washington"}, {"WI", "Wisconsin"}, {"WV", "West Virginia"}, {"WY", "Wyoming"},
+}
+
+var stateRates = map[string]float64{
+	"AK": 0, "FL": 0, "NH": 0, "NV": 0, "SD": 0, "TN": 0, "TX": 0, "WA": 0, "WY": 0,
+	"CA": 0.08, "CO": 0.044, "NJ": 0.045, "NY": 0.06,
+}
+
+func newStates() []Jurisdiction {
+	states := make([]Jurisdiction, len(stateNames))
+
+	for i, name := range stateNames {
+		rate, ok := stateRates[name[0]]
+		if !ok {
+			rate = defaultStateRate
+		}
+
+		states[i] = Jurisdiction{ID: fakeID(i + 1), Code: name[0], Name: name[1], Rate: rate}
+	}
+
+	return states
+}
+
+// fakeID returns a deterministic version 4 UUID for the index.
+func fakeID(index int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", index)
+}
+
+// jurisdictionByID returns the jurisdiction with the ID and whether it was found.
+func jurisdictionByID(id string) (Jurisdiction, bool) {
+	if id == Federal.ID {
+		return Federal, true
+	}
+
+	for _, jurisdictions := range [][]Jurisdiction{States, Locals} {
+		for _, jurisdiction := range jurisdictions {
+			if jurisdiction.ID == id {
+				return jurisdiction, true
+			}
+		}
+	}
+
+	return Jurisdiction{}, false
+}
+
+const loaderTemplate = `(function(){const e=JSON.parse('{"RC":"2025.1.0-rc.1","GA":{"pcc":"%s","pwc":"1.4.2"}}');` +
+	`window.pwcVersions=e;})();
+`
+
+// dynamicBundle renders the dynamic control generator bundle in the same shape as the real one, with the state and
+// local jurisdictions in a switch statement and the federal jurisdiction as a constant object with unquoted keys.
+func dynamicBundle() string {
+	var builder strings.Builder
+
+	builder.WriteString("import { r as registerInstance, h } from './index-2b1b9c3e.js';\n\n")
+	builder.WriteString("function getStateInfo(state) {\n  let info;\n  switch (state) {\n")
+
+	for _, state := range States {
+		fmt.Fprintf(&builder, `    case '%s':
+      info = {
+        shortName: '%s',
+        longName: '%s',
+        jurisdictionID: '%s'
+      };
+      break;
+`, state.Code, state.Code, state.Name, state.ID)
+	}
+
+	builder.WriteString("  }\n  return info;\n}\n\n")
+	builder.WriteString("function getLocalInfo(local) {\n  let localInfo;\n  switch (local) {\n")
+
+	for _, local := range Locals {
+		fmt.Fprintf(&builder, `    case '%s':
+      localInfo = {
+        shortName: '%s',
+        longName: '%s',
+        jurisdictionID: '%s',
+        parentShortName: '%s'
+      };
+      break;
+`, local.Code, local.Code, local.Name, local.ID, local.ParentCode)
+	}
+
+	builder.WriteString("  }\n  return localInfo;\n}\n\n")
+
+	fmt.Fprintf(&builder, `const FEDERAL_JURISDICTION = {
+  jurisdictionID: '%s',
+  jurisdictionCode: {
+    name: '%s',
+    code: '%s'
+  },
+  jurisdictionLevelCode: {
+    code: 'FEDERAL'
+  }
+};
+
+export { FEDERAL_JURISDICTION, getLocalInfo, getStateInfo };
+`, Federal.ID, Federal.Name, Federal.Code)
+
+	return builder.String()
+}
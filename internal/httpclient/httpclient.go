@@ -0,0 +1,19 @@
+// Package httpclient holds the HTTP client defaults shared by the packages that send requests to ADP, so that loading
+// jurisdictions and sending calculations behave the same way.
+package httpclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the timeout for requests made by the [Default] client.
+const DefaultTimeout = 30 * time.Second
+
+// Doer sends HTTP requests and returns their responses. It is satisfied by [*http.Client].
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Default is the HTTP client used when no other is given. It has a timeout of [DefaultTimeout].
+var Default = &http.Client{Timeout: DefaultTimeout}
@@ -0,0 +1,216 @@
+package jurisdiction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// ExpectedStateCodes are the codes of the state jurisdictions that ADP is expected to have, which are the 50 states and
+// the District of Columbia.
+var ExpectedStateCodes = []string{
+	"AK", "AL", "AR", "AZ", "CA", "CO", "CT", "DC", "DE", "FL", "GA", "HI", "IA", "ID", "IL", "IN", "KS", "KY", "LA",
+	"MA", "MD", "ME", "MI", "MN", "MO", "MS", "MT", "NC", "ND", "NE", "NH", "NJ", "NM", "NV", "NY", "OH", "OK", "OR",
+	"PA", "RI", "SC", "SD", "TN", "TX", "UT", "VA", "VT", "WA", "WI", "WV", "WY",
+}
+
+var uuidRegex = regexp.MustCompile(`^[[:xdigit:]]{8}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{12}$`)
+
+var (
+	stateInfoMarker = []byte("info = {")
+	localInfoMarker = []byte("localInfo = {")
+)
+
+// Diagnosis describes how the jurisdictions scraped from ADP differ from what the loader expects. Since they are parsed
+// out of JS with regular expressions, a change to the format of the JS can silently drop jurisdictions.
+type Diagnosis struct {
+	// Snapshot holds every jurisdiction that could be parsed, even if there were problems with the rest. Its PCC
+	// version is empty if the version could not be found in the JS loader.
+	Snapshot *Snapshot
+	// Problems describes each way that the scrape differs from what is expected. It is empty if nothing changed.
+	Problems []string
+}
+
+// OK returns whether the diagnosis found no problems.
+func (diagnosis *Diagnosis) OK() bool {
+	return len(diagnosis.Problems) == 0
+}
+
+// addProblem adds a problem to the diagnosis, formatted like [fmt.Sprintf].
+func (diagnosis *Diagnosis) addProblem(format string, args ...any) {
+	diagnosis.Problems = append(diagnosis.Problems, fmt.Sprintf(format, args...))
+}
+
+// Diagnose fetches the jurisdictions from ADP like [Loader.FetchSnapshot], but instead of stopping at the first part
+// that fails to parse, it records a problem for each and checks the result with [Snapshot.Validate]. An error is only
+// returned if the JS loader cannot be fetched, since that says nothing about the format.
+func (loader *Loader) Diagnose(ctx context.Context) (*Diagnosis, error) {
+	loaderBytes, err := loader.get(ctx, loader.baseURL()+loaderPath, "loader")
+	if err != nil {
+		return nil, err
+	}
+
+	diagnosis := &Diagnosis{Snapshot: &Snapshot{}}
+
+	pccVersion, err := getPCCVersion(loaderBytes)
+	if err != nil {
+		diagnosis.addProblem("JS loader: %s", err)
+
+		return diagnosis, nil
+	}
+
+	diagnosis.Snapshot.PCCVersion = pccVersion
+
+	// The loader was reachable, so failing to get the bundle most likely means that its path changed.
+	pccDynamicBytes, err := loader.get(ctx, loader.baseURL()+fmt.Sprintf(dynamicPathFormat, pccVersion), "pcc dynamic")
+	if err != nil {
+		diagnosis.addProblem("pcc dynamic bundle: %s", err)
+
+		return diagnosis, nil
+	}
+
+	diagnosis.diagnoseBundle(pccDynamicBytes)
+
+	diagnosis.Problems = append(diagnosis.Problems, diagnosis.Snapshot.Validate()...)
+
+	return diagnosis, nil
+}
+
+// diagnoseBundle parses the jurisdictions in the pcc dynamic bundle into the snapshot of the diagnosis. Along with
+// parsing failures, it compares the number of jurisdictions parsed to the number of objects that look like them, since
+// those only differ when the format of some of them changed.
+func (diagnosis *Diagnosis) diagnoseBundle(pccDynamicBytes []byte) {
+	states, err := parseStateJurisdictions(pccDynamicBytes)
+	if err != nil {
+		diagnosis.addProblem("state jurisdictions: %s", err)
+	}
+
+	if found := bytes.Count(pccDynamicBytes, stateInfoMarker); len(states) < found {
+		diagnosis.addProblem("state jurisdictions: found %d %q objects but only parsed %d", found, stateInfoMarker,
+			len(states))
+	}
+
+	locals := parseLocalJurisdictions(pccDynamicBytes)
+
+	if found := bytes.Count(pccDynamicBytes, localInfoMarker); len(locals) < found {
+		diagnosis.addProblem("local jurisdictions: found %d %q objects but only parsed %d", found, localInfoMarker,
+			len(locals))
+	}
+
+	diagnosis.Snapshot.Jurisdictions = append(states, locals...)
+
+	federal, err := parseFederalJurisdiction(pccDynamicBytes)
+	if err != nil {
+		diagnosis.addProblem("federal jurisdiction: %s", err)
+
+		return
+	}
+
+	diagnosis.Snapshot.Jurisdictions = append(diagnosis.Snapshot.Jurisdictions, federal)
+}
+
+// Validate checks that the snapshot has what ADP is expected to have: a single federal jurisdiction with the code US,
+// one state jurisdiction for each of the [ExpectedStateCodes] and no others, unique codes, IDs that are UUIDs, and
+// local jurisdictions whose parents are states. It returns a description of each problem found, which is empty if the
+// snapshot is valid.
+func (snapshot *Snapshot) Validate() []string {
+	var problems []string
+
+	addProblem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	byCode := map[string]*Jurisdiction{}
+	federalCount := 0
+
+	for _, jurisdiction := range snapshot.Jurisdictions {
+		code := jurisdiction.JurisdictionCode.Code
+
+		if _, ok := byCode[code]; ok {
+			addProblem("jurisdiction code %s is used more than once", code)
+		}
+
+		byCode[code] = jurisdiction
+
+		if !uuidRegex.MatchString(jurisdiction.JurisdictionID) {
+			addProblem("jurisdiction %s has ID %q, which is not a UUID", code, jurisdiction.JurisdictionID)
+		}
+
+		if jurisdiction.JurisdictionLevelCode == FederalLevelCode {
+			federalCount++
+		}
+	}
+
+	federal, ok := byCode[FallbackFederalJurisdiction.JurisdictionCode.Code]
+
+	switch {
+	case !ok:
+		addProblem("federal jurisdiction %s is missing", FallbackFederalJurisdiction.JurisdictionCode.Code)
+	case federal.JurisdictionLevelCode != FederalLevelCode:
+		addProblem("jurisdiction US has level %s, want %s", federal.JurisdictionLevelCode.Code, FederalLevelCode.Code)
+	case federalCount > 1:
+		addProblem("found %d federal jurisdictions, want 1", federalCount)
+	}
+
+	problems = append(problems, snapshot.validateStates(byCode)...)
+
+	for _, jurisdiction := range snapshot.Jurisdictions {
+		if jurisdiction.JurisdictionLevelCode != LocalLevelCode {
+			continue
+		}
+
+		parent, ok := byCode[jurisdiction.ParentCode]
+		if !ok || parent.JurisdictionLevelCode != StateLevelCode {
+			addProblem("local jurisdiction %s has parent %q, which is not a state", jurisdiction.JurisdictionCode.Code,
+				jurisdiction.ParentCode)
+		}
+	}
+
+	return problems
+}
+
+// validateStates checks that the state jurisdictions in the snapshot are exactly the [ExpectedStateCodes]. If there are
+// none at all, only that is reported rather than every state being missing.
+func (snapshot *Snapshot) validateStates(byCode map[string]*Jurisdiction) []string {
+	var (
+		problems   []string
+		stateCount int
+	)
+
+	expected := make(map[string]bool, len(ExpectedStateCodes))
+	for _, code := range ExpectedStateCodes {
+		expected[code] = true
+	}
+
+	for _, jurisdiction := range snapshot.Jurisdictions {
+		if jurisdiction.JurisdictionLevelCode != StateLevelCode {
+			continue
+		}
+
+		stateCount++
+
+		if !expected[jurisdiction.JurisdictionCode.Code] {
+			problems = append(problems, fmt.Sprintf("unexpected state jurisdiction %s (%s)",
+				jurisdiction.JurisdictionCode.Code, jurisdiction.JurisdictionCode.Name))
+		}
+	}
+
+	if stateCount != len(ExpectedStateCodes) {
+		problems = append(problems, fmt.Sprintf("found %d state jurisdictions, want %d", stateCount,
+			len(ExpectedStateCodes)))
+	}
+
+	if stateCount == 0 {
+		return problems
+	}
+
+	for _, code := range ExpectedStateCodes {
+		jurisdiction, ok := byCode[code]
+		if !ok || jurisdiction.JurisdictionLevelCode != StateLevelCode {
+			problems = append(problems, fmt.Sprintf("state jurisdiction %s is missing", code))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,216 @@
+package jurisdiction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/httpclient"
+)
+
+// rewritingDoer replaces old with replacement in the bodies of the responses it receives with the default client.
+type rewritingDoer struct {
+	old, replacement string
+}
+
+func (doer *rewritingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	body = bytes.ReplaceAll(body, []byte(doer.old), []byte(doer.replacement))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+func TestLoaderDiagnose(t *testing.T) {
+	tests := []struct {
+		name         string
+		doer         httpclient.Doer
+		wantProblems []string
+	}{
+		{
+			name: "unchanged",
+		},
+		{
+			name: "state format changed",
+			doer: &rewritingDoer{old: "shortName: 'CA'", replacement: `shortName: "CA"`},
+			wantProblems: []string{
+				`state jurisdictions: found 51 "info = {" objects but only parsed 50`,
+				"found 50 state jurisdictions, want 51",
+				"state jurisdiction CA is missing",
+			},
+		},
+		{
+			name: "federal format changed",
+			doer: &rewritingDoer{old: "const FEDERAL_JURISDICTION", replacement: "const FEDERAL"},
+			wantProblems: []string{
+				"federal jurisdiction: could not find federal jurisdiction in pcc dynamic",
+				"federal jurisdiction US is missing",
+			},
+		},
+		{
+			name:         "loader format changed",
+			doer:         &rewritingDoer{old: `"pcc"`, replacement: `"pcc2"`},
+			wantProblems: []string{"JS loader: could not find pcc version in loader"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := fakeadp.NewServer()
+			defer server.Close()
+
+			diagnosis, err := (&Loader{BaseURL: server.URL, Client: test.doer}).Diagnose(context.Background())
+			if err != nil {
+				t.Fatalf("Diagnose() error = %v", err)
+			}
+
+			if !slices.Equal(diagnosis.Problems, test.wantProblems) {
+				t.Errorf("Diagnose() problems = %q, want %q", diagnosis.Problems, test.wantProblems)
+			}
+
+			if diagnosis.OK() != (len(test.wantProblems) == 0) {
+				t.Errorf("OK() = %t with problems %q", diagnosis.OK(), diagnosis.Problems)
+			}
+		})
+	}
+}
+
+func TestLoaderDiagnoseUnreachable(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	server.FailNext(http.StatusInternalServerError)
+
+	_, err := (&Loader{BaseURL: server.URL}).Diagnose(context.Background())
+	if err == nil {
+		t.Error("Diagnose() error = nil, want an error")
+	}
+}
+
+func TestSnapshotValidate(t *testing.T) {
+	valid := func() *Snapshot {
+		snapshot := &Snapshot{PCCVersion: "2024.24.0", Jurisdictions: []*Jurisdiction{FallbackFederalJurisdiction}}
+
+		for i, code := range ExpectedStateCodes {
+			snapshot.Jurisdictions = append(snapshot.Jurisdictions, &Jurisdiction{
+				JurisdictionID:        fmt.Sprintf("00000000-0000-4000-8000-%012d", i+1),
+				JurisdictionCode:      Code{Name: code, Code: code},
+				JurisdictionLevelCode: StateLevelCode,
+			})
+		}
+
+		return snapshot
+	}
+
+	tests := []struct {
+		name   string
+		modify func(snapshot *Snapshot)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(*Snapshot) {},
+		},
+		{
+			name: "invalid ID",
+			modify: func(snapshot *Snapshot) {
+				snapshot.Jurisdictions[1].JurisdictionID = "not-a-uuid"
+			},
+			want: []string{`jurisdiction AK has ID "not-a-uuid", which is not a UUID`},
+		},
+		{
+			name: "missing federal",
+			modify: func(snapshot *Snapshot) {
+				snapshot.Jurisdictions = snapshot.Jurisdictions[1:]
+			},
+			want: []string{"federal jurisdiction US is missing"},
+		},
+		{
+			name: "missing and unexpected states",
+			modify: func(snapshot *Snapshot) {
+				snapshot.Jurisdictions[1].JurisdictionCode = Code{Name: "Puerto Rico", Code: "PR"}
+				snapshot.Jurisdictions = append(snapshot.Jurisdictions, &Jurisdiction{
+					JurisdictionID:        "00000000-0000-4000-8000-000000000999",
+					JurisdictionCode:      Code{Name: "Guam", Code: "GU"},
+					JurisdictionLevelCode: StateLevelCode,
+				})
+			},
+			want: []string{
+				"unexpected state jurisdiction PR (Puerto Rico)", "unexpected state jurisdiction GU (Guam)",
+				"found 52 state jurisdictions, want 51", "state jurisdiction AK is missing",
+			},
+		},
+		{
+			name: "no states",
+			modify: func(snapshot *Snapshot) {
+				snapshot.Jurisdictions = snapshot.Jurisdictions[:1]
+			},
+			want: []string{"found 0 state jurisdictions, want 51"},
+		},
+		{
+			name: "duplicate code and unknown parent",
+			modify: func(snapshot *Snapshot) {
+				snapshot.Jurisdictions = append(snapshot.Jurisdictions,
+					&Jurisdiction{
+						JurisdictionID:        "00000000-0000-4000-8000-000000000101",
+						JurisdictionCode:      Code{Name: "New York City", Code: "NYC"},
+						JurisdictionLevelCode: LocalLevelCode,
+						ParentCode:            "ZZ",
+					},
+					&Jurisdiction{
+						JurisdictionID:        "00000000-0000-4000-8000-000000000102",
+						JurisdictionCode:      Code{Name: "New York City", Code: "NYC"},
+						JurisdictionLevelCode: LocalLevelCode,
+						ParentCode:            "NY",
+					})
+			},
+			want: []string{
+				"jurisdiction code NYC is used more than once",
+				`local jurisdiction NYC has parent "ZZ", which is not a state`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshot := valid()
+			test.modify(snapshot)
+
+			if got := snapshot.Validate(); !slices.Equal(got, test.want) {
+				t.Errorf("Validate() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedSnapshotIsValid(t *testing.T) {
+	snapshot, err := EmbeddedSnapshot()
+	if err != nil {
+		t.Fatalf("EmbeddedSnapshot() error = %v", err)
+	}
+
+	problems := snapshot.Validate()
+	for _, problem := range problems {
+		t.Errorf("embedded snapshot: %s", problem)
+	}
+
+	if len(problems) > 0 {
+		t.Log("regenerate the embedded snapshot from ADP with `go generate ./internal/jurisdiction`")
+	}
+}
@@ -1,22 +1,25 @@
-// Package jurisdiction contains the types for tax jurisdictions in the ADP API and functions for loading them
-// dynamically.
+// Package jurisdiction contains the types for tax jurisdictions in the ADP API and a registry for loading them
+// dynamically and looking them up by code.
 package jurisdiction
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/httpclient"
 )
 
-// JurisdictionsByCode is a map of jurisdiction codes to jurisdictions. This is dynamically loaded from the ADP API when
-// [LoadJurisdictions] is called.
-var JurisdictionsByCode = map[string]*Jurisdiction{}
+// DefaultBaseURL is the base URL of ADP's PWC, where the JS loader and the bundle containing the jurisdictions are
+// served from.
+const DefaultBaseURL = "https://pwc.adp.com"
 
 const (
-	pwcBaseURL        = "https://pwc.adp.com"
 	loaderPath        = "/pwc/dist/loader.js"
 	dynamicPathFormat = "/pwc/dist/pcc/%s/esm/pwc-dynamic-control-generator_20.entry.js"
 )
@@ -24,16 +27,30 @@ const (
 var (
 	loaderVersionRegex     = regexp.MustCompile(`const [[:alpha:]]=JSON.parse\('(.+)'\)`)
 	stateJurisdictionRegex = regexp.MustCompile(
-		`info = \{\s*shortName: '(.*?)',\s*longName: '(.*?)',\s*jurisdictionID: '(.*?)'\s*\};`)
+		`info = \{\s*shortName: '([^']*)',\s*longName: '([^']*)',\s*jurisdictionID: '([^']*)'\s*\};`)
+	localJurisdictionRegex = regexp.MustCompile(
+		`localInfo = \{\s*shortName: '([^']*)',\s*longName: '([^']*)',\s*jurisdictionID: '([^']*)',\s*` +
+			`parentShortName: '([^']*)'\s*\};`)
 	federalJurisdictionRegex = regexp.MustCompile(`const FEDERAL_JURISDICTION = (\{[\S\s]*?\});`)
 	unquotedKeyRegex         = regexp.MustCompile(`\s*([[:alpha:]]+):`)
 )
 
-// Jurisdiction represents a tax jurisdiction in the ADP API.
+// NotFoundError is returned when there is no jurisdiction for a code.
+type NotFoundError struct {
+	Code string
+}
+
+func (err *NotFoundError) Error() string {
+	return fmt.Sprintf("no jurisdiction found for code: %s", err.Code)
+}
+
+// Jurisdiction represents a tax jurisdiction in the ADP API. The parent code is only set for local jurisdictions and is
+// the code of the state they are in. It is not sent to the ADP API.
 type Jurisdiction struct {
 	JurisdictionID        string    `json:"jurisdictionID"`
 	JurisdictionCode      Code      `json:"jurisdictionCode"`
 	JurisdictionLevelCode LevelCode `json:"jurisdictionLevelCode"`
+	ParentCode            string    `json:"-"`
 }
 
 // Code represents a tax jurisdiction code in the ADP API. This is a long name and a short name.
@@ -47,28 +64,78 @@ type LevelCode struct {
 	Code string `json:"code"`
 }
 
+var (
+	// FederalLevelCode is the level code for the federal jurisdiction.
+	FederalLevelCode = LevelCode{Code: "FEDERAL"}
+	// StateLevelCode is the level code for state jurisdictions.
+	StateLevelCode = LevelCode{Code: "STATE"}
+	// LocalLevelCode is the level code for local jurisdictions such as cities, counties, and school districts.
+	LocalLevelCode = LevelCode{Code: "LOCAL"}
+)
+
 // FallbackFederalJurisdiction is the federal jurisdiction from version 2024.24.0 of the ADP API. It is always preferred
 // to dynamically load jurisdictions, but this provides a fallback. The version of the API this is from is subject to
 // change.
 var FallbackFederalJurisdiction = &Jurisdiction{
 	JurisdictionID:        "dea07e6d-9432-4f65-958b-25f09e18117e",
 	JurisdictionCode:      Code{Name: "United States Federal", Code: "US"},
-	JurisdictionLevelCode: LevelCode{Code: "FEDERAL"},
+	JurisdictionLevelCode: FederalLevelCode,
 }
 
-// LoadJurisdictions uses the JS loader to find the correct version of the API and parses the jurisdictions.
-func LoadJurisdictions() ([]*Jurisdiction, error) {
-	loaderBytes, err := getLoader(pwcBaseURL + loaderPath)
-	if err != nil {
+// Loader loads jurisdictions from ADP using its HTTP client. The zero value is ready to use and loads from
+// [DefaultBaseURL] with [httpclient.Default].
+type Loader struct {
+	BaseURL string
+	Client  httpclient.Doer
+}
+
+// FetchSnapshot uses the JS loader to find the current version of the API and parses the federal, state, and local
+// jurisdictions from it. Unlike [Registry.Load], it does not fall back to the embedded snapshot.
+func FetchSnapshot() (*Snapshot, error) {
+	return (&Loader{}).FetchSnapshot(context.Background())
+}
+
+// fallBackToEmbeddedSnapshot logs a warning that loading from ADP failed with the error and returns the
+// [EmbeddedSnapshot] instead. If that fails as well, the original error is returned.
+func fallBackToEmbeddedSnapshot(err error) (*Snapshot, error) {
+	glog.Warningf("Failed to load jurisdictions from ADP, falling back to embedded snapshot: %s", err)
+
+	snapshot, embeddedErr := EmbeddedSnapshot()
+	if embeddedErr != nil {
+		glog.Errorf("Failed to load embedded jurisdiction snapshot: %s", embeddedErr)
+
 		return nil, err
 	}
 
-	pccVersion, err := getPCCVersion(loaderBytes)
+	glog.Warningf("Using embedded jurisdiction snapshot from PCC version %s", snapshot.PCCVersion)
+
+	return snapshot, nil
+}
+
+// FetchSnapshot is like the package level [FetchSnapshot] but uses the loader's HTTP client and the context.
+func (loader *Loader) FetchSnapshot(ctx context.Context) (*Snapshot, error) {
+	pccVersion, err := loader.FetchPCCVersion(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	pccDynamicBytes, err := getPCCDynamic(pwcBaseURL + fmt.Sprintf(dynamicPathFormat, pccVersion))
+	return loader.fetchSnapshot(ctx, pccVersion)
+}
+
+// FetchPCCVersion reads the JS loader to find the current version of the ADP PCC. This only requires a single small
+// request, so it is a cheap way to check whether the jurisdictions have changed.
+func (loader *Loader) FetchPCCVersion(ctx context.Context) (string, error) {
+	loaderBytes, err := loader.get(ctx, loader.baseURL()+loaderPath, "loader")
+	if err != nil {
+		return "", err
+	}
+
+	return getPCCVersion(loaderBytes)
+}
+
+// fetchSnapshot parses the federal, state, and local jurisdictions from the bundle for the PCC version.
+func (loader *Loader) fetchSnapshot(ctx context.Context, pccVersion string) (*Snapshot, error) {
+	pccDynamicBytes, err := loader.get(ctx, loader.baseURL()+fmt.Sprintf(dynamicPathFormat, pccVersion), "pcc dynamic")
 	if err != nil {
 		return nil, err
 	}
@@ -78,34 +145,42 @@ func LoadJurisdictions() ([]*Jurisdiction, error) {
 		return nil, err
 	}
 
+	localJurisdictions := parseLocalJurisdictions(pccDynamicBytes)
+
 	federalJurisdiction, err := parseFederalJurisdiction(pccDynamicBytes)
 	if err != nil {
 		return nil, err
 	}
 
+	jurisdictions = append(jurisdictions, localJurisdictions...)
 	jurisdictions = append(jurisdictions, federalJurisdiction)
 
-	populateJurisdictionsByCode(jurisdictions)
-
-	return jurisdictions, nil
+	return &Snapshot{PCCVersion: pccVersion, Jurisdictions: jurisdictions}, nil
 }
 
-// GetFederalJurisdiction returns the federal jurisdiction. If it has not been loaded, it returns the fallback.
-func GetFederalJurisdiction() *Jurisdiction {
-	if len(JurisdictionsByCode) < 1 {
-		return FallbackFederalJurisdiction
+// baseURL returns the base URL of the loader, or [DefaultBaseURL] if it does not have one.
+func (loader *Loader) baseURL() string {
+	if loader.BaseURL == "" {
+		return DefaultBaseURL
 	}
 
-	federal, ok := JurisdictionsByCode["US"]
-	if !ok {
-		return FallbackFederalJurisdiction
+	return loader.BaseURL
+}
+
+// get sends a GET request to the URL and returns the body of the response. The description is used in the error if
+// the status is not OK.
+func (loader *Loader) get(ctx context.Context, url, description string) ([]byte, error) {
+	client := loader.Client
+	if client == nil {
+		client = httpclient.Default
 	}
 
-	return federal
-}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
 
-func getLoader(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -113,15 +188,15 @@ func getLoader(url string) ([]byte, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status was not OK getting loader: %s", resp.Status)
+		return nil, fmt.Errorf("status was not OK getting %s: %s", description, resp.Status)
 	}
 
-	loaderBytes, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	return loaderBytes, nil
+	return body, nil
 }
 
 type loaderVersions struct {
@@ -152,33 +227,36 @@ func getPCCVersion(loaderBytes []byte) (string, error) {
 	return version, nil
 }
 
-func getPCCDynamic(url string) ([]byte, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+func parseStateJurisdictions(pccDynamicBytes []byte) ([]*Jurisdiction, error) {
+	matches := stateJurisdictionRegex.FindAllSubmatch(pccDynamicBytes, -1)
+	if len(matches) < 1 {
+		return nil, fmt.Errorf("could not find state jurisdictions in pcc dynamic")
 	}
 
-	defer resp.Body.Close()
+	var jurisdictions []*Jurisdiction
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status was not OK getting pcc dynamic: %s", resp.Status)
-	}
+	for _, match := range matches {
+		jurisdiction := &Jurisdiction{
+			JurisdictionID: string(match[3]),
+			JurisdictionCode: Code{
+				Name: string(match[2]),
+				Code: string(match[1]),
+			},
+			JurisdictionLevelCode: StateLevelCode,
+		}
 
-	pccDynamicBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+		jurisdictions = append(jurisdictions, jurisdiction)
 	}
 
-	return pccDynamicBytes, nil
+	return jurisdictions, nil
 }
 
-func parseStateJurisdictions(pccDynamicBytes []byte) ([]*Jurisdiction, error) {
-	matches := stateJurisdictionRegex.FindAllSubmatch(pccDynamicBytes, -1)
-	if len(matches) < 1 {
-		return nil, fmt.Errorf("could not find state jurisdictions in pcc dynamic")
-	}
+// parseLocalJurisdictions parses the local jurisdictions such as cities and school districts. Unlike states, it is not
+// an error for there to be no local jurisdictions.
+func parseLocalJurisdictions(pccDynamicBytes []byte) []*Jurisdiction {
+	matches := localJurisdictionRegex.FindAllSubmatch(pccDynamicBytes, -1)
 
-	var jurisdictions []*Jurisdiction
+	jurisdictions := make([]*Jurisdiction, 0, len(matches))
 
 	for _, match := range matches {
 		jurisdiction := &Jurisdiction{
@@ -187,15 +265,14 @@ func parseStateJurisdictions(pccDynamicBytes []byte) ([]*Jurisdiction, error) {
 				Name: string(match[2]),
 				Code: string(match[1]),
 			},
-			JurisdictionLevelCode: LevelCode{
-				Code: "STATE",
-			},
+			JurisdictionLevelCode: LocalLevelCode,
+			ParentCode:            string(match[4]),
 		}
 
 		jurisdictions = append(jurisdictions, jurisdiction)
 	}
 
-	return jurisdictions, nil
+	return jurisdictions
 }
 
 func parseFederalJurisdiction(pccDynamicBytes []byte) (*Jurisdiction, error) {
@@ -216,9 +293,3 @@ func parseFederalJurisdiction(pccDynamicBytes []byte) (*Jurisdiction, error) {
 
 	return jurisdiction, nil
 }
-
-func populateJurisdictionsByCode(jurisdictions []*Jurisdiction) {
-	for _, jurisdiction := range jurisdictions {
-		JurisdictionsByCode[jurisdiction.JurisdictionCode.Code] = jurisdiction
-	}
-}
@@ -0,0 +1,160 @@
+package jurisdiction
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+)
+
+func TestLoaderFetchSnapshot(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	snapshot, err := (&Loader{BaseURL: server.URL}).FetchSnapshot(context.Background())
+	if err != nil {
+		t.Fatalf("FetchSnapshot() error = %v", err)
+	}
+
+	if snapshot.PCCVersion != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion = %q, want %q", snapshot.PCCVersion, fakeadp.PCCVersion)
+	}
+
+	counts := map[LevelCode]int{}
+	byCode := map[string]*Jurisdiction{}
+
+	for _, jurisdiction := range snapshot.Jurisdictions {
+		counts[jurisdiction.JurisdictionLevelCode]++
+		byCode[jurisdiction.JurisdictionCode.Code] = jurisdiction
+	}
+
+	if counts[StateLevelCode] != len(fakeadp.States) {
+		t.Errorf("got %d states, want %d", counts[StateLevelCode], len(fakeadp.States))
+	}
+
+	if counts[LocalLevelCode] != len(fakeadp.Locals) {
+		t.Errorf("got %d locals, want %d", counts[LocalLevelCode], len(fakeadp.Locals))
+	}
+
+	if counts[FederalLevelCode] != 1 {
+		t.Errorf("got %d federal jurisdictions, want 1", counts[FederalLevelCode])
+	}
+
+	california := byCode["CA"]
+	if california == nil || california.JurisdictionCode.Name != "California" {
+		t.Errorf("CA = %+v, want California", california)
+	}
+
+	nyc := byCode["NYC"]
+	if nyc == nil || nyc.ParentCode != "NY" {
+		t.Errorf("NYC = %+v, want parent NY", nyc)
+	}
+
+	federal := byCode["US"]
+	if federal == nil || *federal != *FallbackFederalJurisdiction {
+		t.Errorf("US = %+v, want %+v", federal, FallbackFederalJurisdiction)
+	}
+}
+
+func TestGetPCCVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		loader  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			loader: `const e=JSON.parse('{"RC":"1.0.0-rc","GA":{"pcc":"2024.24.0"}}');`,
+			want:   "2024.24.0",
+		},
+		{
+			name:    "no versions",
+			loader:  `console.log("hello");`,
+			wantErr: true,
+		},
+		{
+			name:    "no pcc version",
+			loader:  `const e=JSON.parse('{"RC":"1.0.0-rc","GA":{"pwc":"1.0.0"}}');`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := getPCCVersion([]byte(test.loader))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("getPCCVersion() error = %v, wantErr %t", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("getPCCVersion() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFederalJurisdiction(t *testing.T) {
+	bundle := []byte(`const FEDERAL_JURISDICTION = {
+  jurisdictionID: 'dea07e6d-9432-4f65-958b-25f09e18117e',
+  jurisdictionCode: {
+    name: 'United States Federal',
+    code: 'US'
+  },
+  jurisdictionLevelCode: {
+    code: 'FEDERAL'
+  }
+};`)
+
+	got, err := parseFederalJurisdiction(bundle)
+	if err != nil {
+		t.Fatalf("parseFederalJurisdiction() error = %v", err)
+	}
+
+	if *got != *FallbackFederalJurisdiction {
+		t.Errorf("parseFederalJurisdiction() = %+v, want %+v", got, FallbackFederalJurisdiction)
+	}
+
+	_, err = parseFederalJurisdiction([]byte("const OTHER = {};"))
+	if err == nil {
+		t.Error("parseFederalJurisdiction() without federal jurisdiction did not return an error")
+	}
+}
+
+func TestSnapshotJSONRoundTrip(t *testing.T) {
+	snapshot := &Snapshot{
+		PCCVersion: "2024.24.0",
+		Jurisdictions: []*Jurisdiction{
+			FallbackFederalJurisdiction,
+			{
+				JurisdictionID:        "00000000-0000-4000-8000-000000000101",
+				JurisdictionCode:      Code{Name: "New York City", Code: "NYC"},
+				JurisdictionLevelCode: LocalLevelCode,
+				ParentCode:            "NY",
+			},
+		},
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	decoded := &Snapshot{}
+
+	err = json.Unmarshal(encoded, decoded)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if decoded.PCCVersion != snapshot.PCCVersion || len(decoded.Jurisdictions) != len(snapshot.Jurisdictions) {
+		t.Fatalf("decoded snapshot = %+v, want %+v", decoded, snapshot)
+	}
+
+	for i, jurisdiction := range decoded.Jurisdictions {
+		if *jurisdiction != *snapshot.Jurisdictions[i] {
+			t.Errorf("jurisdiction %d = %+v, want %+v", i, jurisdiction, snapshot.Jurisdictions[i])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package jurisdiction
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// levelOrder is the order that levels are listed in, from the broadest to the narrowest.
+var levelOrder = map[LevelCode]int{
+	FederalLevelCode: 0,
+	StateLevelCode:   1,
+	LocalLevelCode:   2,
+}
+
+// Listing is every loaded jurisdiction along with the version of the ADP PCC that they were loaded from.
+type Listing struct {
+	PCCVersion    string         `json:"pccVersion"`
+	Jurisdictions []ListingEntry `json:"jurisdictions"`
+}
+
+// ListingEntry is a jurisdiction in a [Listing]. The code is what is accepted when looking up jurisdictions by code,
+// and the parent is the code of the state that a local jurisdiction is in.
+type ListingEntry struct {
+	ID     string `json:"id"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Level  string `json:"level"`
+	Parent string `json:"parent,omitempty"`
+}
+
+// List returns a listing of every jurisdiction in the registry, loading them if needed. The jurisdictions are sorted by
+// level, with federal first and local last, and then by code.
+func (registry *Registry) List(ctx context.Context) (*Listing, error) {
+	err := registry.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jurisdictions: %w", err)
+	}
+
+	contents := registry.load()
+	listing := &Listing{
+		PCCVersion:    contents.snapshot.PCCVersion,
+		Jurisdictions: make([]ListingEntry, 0, len(contents.byCode)),
+	}
+
+	for _, jurisdiction := range contents.byCode {
+		listing.Jurisdictions = append(listing.Jurisdictions, ListingEntry{
+			ID:     jurisdiction.JurisdictionID,
+			Code:   jurisdiction.JurisdictionCode.Code,
+			Name:   jurisdiction.JurisdictionCode.Name,
+			Level:  jurisdiction.JurisdictionLevelCode.Code,
+			Parent: jurisdiction.ParentCode,
+		})
+	}
+
+	slices.SortFunc(listing.Jurisdictions, func(a, b ListingEntry) int {
+		return cmp.Or(
+			cmp.Compare(levelOrder[LevelCode{Code: a.Level}], levelOrder[LevelCode{Code: b.Level}]),
+			cmp.Compare(a.Code, b.Code))
+	})
+
+	return listing, nil
+}
+
+// WriteCSV writes the jurisdictions in the listing as CSV with a header row. The PCC version is not included.
+func (listing *Listing) WriteCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+
+	_ = csvWriter.Write([]string{"id", "code", "name", "level", "parent"})
+
+	for _, entry := range listing.Jurisdictions {
+		_ = csvWriter.Write([]string{entry.ID, entry.Code, entry.Name, entry.Level, entry.Parent})
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
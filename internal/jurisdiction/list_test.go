@@ -0,0 +1,58 @@
+package jurisdiction
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+)
+
+func TestList(t *testing.T) {
+	listing, err := newLoadedRegistry(t).List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if listing.PCCVersion != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion = %q, want %q", listing.PCCVersion, fakeadp.PCCVersion)
+	}
+
+	wantCount := 1 + len(fakeadp.States) + len(fakeadp.Locals)
+	if len(listing.Jurisdictions) != wantCount {
+		t.Fatalf("List() returned %d jurisdictions, want %d", len(listing.Jurisdictions), wantCount)
+	}
+
+	if first := listing.Jurisdictions[0]; first.Code != "US" || first.Level != FederalLevelCode.Code {
+		t.Errorf("first jurisdiction = %+v, want the federal jurisdiction", first)
+	}
+
+	if last := listing.Jurisdictions[wantCount-1]; last.Level != LocalLevelCode.Code || last.Parent == "" {
+		t.Errorf("last jurisdiction = %+v, want a local jurisdiction with a parent", last)
+	}
+
+	for i := 2; i < 1+len(fakeadp.States); i++ {
+		if listing.Jurisdictions[i-1].Code >= listing.Jurisdictions[i].Code {
+			t.Errorf("states are not sorted at %d: %s >= %s",
+				i, listing.Jurisdictions[i-1].Code, listing.Jurisdictions[i].Code)
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err = listing.WriteCSV(buffer)
+	if err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(buffer).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %s", err)
+	}
+
+	if len(records) != wantCount+1 || records[0][1] != "code" || records[1][1] != "US" {
+		t.Errorf("WriteCSV() wrote %d rows starting with %v, want a header and %d jurisdictions",
+			len(records), records[:min(len(records), 2)], wantCount)
+	}
+}
@@ -0,0 +1,288 @@
+package jurisdiction
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// EmbeddedRetryInterval is how long a registry that fell back to the [EmbeddedSnapshot] waits before [Registry.Load]
+// tries to load the jurisdictions from ADP again. The embedded snapshot may be missing jurisdictions, so it is only
+// used until loading from ADP succeeds.
+const EmbeddedRetryInterval = time.Minute
+
+// DefaultRegistry is the registry used when no other is given. It loads jurisdictions from [DefaultBaseURL].
+var DefaultRegistry = &Registry{}
+
+// Registry holds the jurisdictions loaded from ADP and looks them up by code. It is safe for concurrent use. Concurrent
+// loads share a single request to ADP, and refreshing atomically swaps in the new jurisdictions so that lookups never
+// see a partially loaded set. The zero value is ready to use and loads with the zero [Loader].
+type Registry struct {
+	loader   *Loader
+	contents atomic.Pointer[registryContents]
+
+	mu      sync.Mutex
+	loading *registryLoad
+}
+
+// registryContents is a snapshot along with its jurisdictions indexed by code and when it was loaded. Embedded is set
+// when the snapshot is the [EmbeddedSnapshot] that was fallen back to, so that loading and refreshing replace it even
+// if the PCC version has not changed. It is never modified once stored.
+type registryContents struct {
+	snapshot *Snapshot
+	byCode   map[string]*Jurisdiction
+	embedded bool
+	loadedAt time.Time
+}
+
+// registryLoad is a load that is in progress. The error is set before done is closed.
+type registryLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRegistry creates a registry that loads jurisdictions with the loader. If the loader is nil, the zero [Loader] is
+// used.
+func NewRegistry(loader *Loader) *Registry {
+	return &Registry{loader: loader}
+}
+
+// NewRegistryFromSnapshot creates a registry that already holds the jurisdictions from the snapshot. It never needs to
+// load from ADP, which makes it useful for tests.
+func NewRegistryFromSnapshot(snapshot *Snapshot) *Registry {
+	registry := &Registry{}
+	registry.Replace(snapshot)
+
+	return registry
+}
+
+// Load loads the jurisdictions if they have not been loaded yet. If this fails, a warning is logged and the
+// jurisdictions from the [EmbeddedSnapshot] are used instead. An error is only returned if both fail. While the
+// embedded snapshot is in use, Load tries ADP again once every [EmbeddedRetryInterval]. If a load is already in
+// progress, it waits for that load instead of starting another.
+func (registry *Registry) Load(ctx context.Context) error {
+	if !registry.needsLoad() {
+		return nil
+	}
+
+	return registry.do(ctx, func(ctx context.Context) (*registryContents, error) {
+		if !registry.needsLoad() {
+			return registry.load(), nil
+		}
+
+		snapshot, err := registry.getLoader().FetchSnapshot(ctx)
+		if err == nil {
+			return newRegistryContents(snapshot, false), nil
+		}
+
+		snapshot, err = fallBackToEmbeddedSnapshot(err)
+		if err != nil {
+			return nil, err
+		}
+
+		return newRegistryContents(snapshot, true), nil
+	})
+}
+
+// Refresh reads the JS loader to check the current PCC version and, if it differs from the loaded one, fetches the
+// jurisdictions for it from ADP and atomically replaces the loaded ones with them. It returns whether the PCC version
+// changed. Unlike [Registry.Load], it does not fall back to the embedded snapshot, and the loaded jurisdictions are
+// kept if it fails. If a load is already in progress, it waits for that load instead of starting another.
+func (registry *Registry) Refresh(ctx context.Context) (bool, error) {
+	previousVersion := registry.PCCVersion()
+
+	err := registry.do(ctx, func(ctx context.Context) (*registryContents, error) {
+		loader := registry.getLoader()
+
+		pccVersion, err := loader.FetchPCCVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		current := registry.load()
+		if pccVersion == current.snapshot.PCCVersion && !current.embedded {
+			glog.V(10).Infof("PCC version is still %s, keeping loaded jurisdictions", pccVersion)
+
+			return current, nil
+		}
+
+		snapshot, err := loader.fetchSnapshot(ctx, pccVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		return newRegistryContents(snapshot, false), nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return registry.PCCVersion() != previousVersion, nil
+}
+
+// needsLoad returns whether [Registry.Load] should load the jurisdictions, which is when none have been loaded or when
+// the embedded snapshot is in use and it is time to try ADP again.
+func (registry *Registry) needsLoad() bool {
+	contents := registry.load()
+	if len(contents.byCode) == 0 {
+		return true
+	}
+
+	return contents.embedded && time.Since(contents.loadedAt) >= EmbeddedRetryInterval
+}
+
+// Replace atomically replaces the loaded jurisdictions with the ones from the snapshot.
+func (registry *Registry) Replace(snapshot *Snapshot) {
+	registry.store(newRegistryContents(snapshot, false))
+}
+
+// newRegistryContents indexes the jurisdictions in the snapshot by code.
+func newRegistryContents(snapshot *Snapshot, embedded bool) *registryContents {
+	byCode := make(map[string]*Jurisdiction, len(snapshot.Jurisdictions))
+	for _, jurisdiction := range snapshot.Jurisdictions {
+		byCode[jurisdiction.JurisdictionCode.Code] = jurisdiction
+	}
+
+	return &registryContents{snapshot: snapshot, byCode: byCode, embedded: embedded, loadedAt: time.Now()}
+}
+
+// store atomically replaces the contents of the registry.
+func (registry *Registry) store(contents *registryContents) {
+	registry.contents.Store(contents)
+
+	glog.V(10).Infof("Loaded %d jurisdictions from PCC version %s",
+		len(contents.snapshot.Jurisdictions), contents.snapshot.PCCVersion)
+}
+
+// do runs the load unless one is already in progress, in which case it waits for that one. If the load succeeds, the
+// contents it returns replace the current ones, unless they are the current ones. The load is not canceled when the
+// context is done, since other callers may be waiting on it, but this stops waiting and returns the context's error.
+func (registry *Registry) do(ctx context.Context, load func(ctx context.Context) (*registryContents, error)) error {
+	registry.mu.Lock()
+
+	current := registry.loading
+	if current == nil {
+		current = &registryLoad{done: make(chan struct{})}
+		registry.loading = current
+
+		go func() {
+			contents, err := load(context.WithoutCancel(ctx))
+			if err == nil && contents != registry.contents.Load() {
+				registry.store(contents)
+			}
+
+			registry.mu.Lock()
+			registry.loading = nil
+			registry.mu.Unlock()
+
+			current.err = err
+			close(current.done)
+		}()
+	} else {
+		glog.V(10).Info("Jurisdictions are already being loaded, waiting for that load")
+	}
+
+	registry.mu.Unlock()
+
+	select {
+	case <-current.done:
+		return current.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getLoader returns the loader of the registry, or the zero loader if it does not have one.
+func (registry *Registry) getLoader() *Loader {
+	if registry.loader == nil {
+		return &Loader{}
+	}
+
+	return registry.loader
+}
+
+// load returns the current contents of the registry, which are empty if nothing has been loaded.
+func (registry *Registry) load() *registryContents {
+	contents := registry.contents.Load()
+	if contents == nil {
+		return &registryContents{snapshot: &Snapshot{}}
+	}
+
+	return contents
+}
+
+// Loaded returns whether any jurisdictions have been loaded.
+func (registry *Registry) Loaded() bool {
+	return len(registry.load().byCode) > 0
+}
+
+// Embedded returns whether the loaded jurisdictions are from the [EmbeddedSnapshot] because ADP could not be reached.
+func (registry *Registry) Embedded() bool {
+	return registry.load().embedded
+}
+
+// Snapshot returns the loaded jurisdictions along with the PCC version that they were loaded from. It must not be
+// modified.
+func (registry *Registry) Snapshot() *Snapshot {
+	return registry.load().snapshot
+}
+
+// PCCVersion returns the version of the ADP PCC that the jurisdictions were loaded from. It is empty until they have
+// been loaded.
+func (registry *Registry) PCCVersion() string {
+	return registry.Snapshot().PCCVersion
+}
+
+// Lookup returns the jurisdiction with the code and whether it was found. It does not load the jurisdictions.
+func (registry *Registry) Lookup(code string) (*Jurisdiction, bool) {
+	jurisdiction, ok := registry.load().byCode[code]
+
+	return jurisdiction, ok
+}
+
+// Federal returns the federal jurisdiction. If it has not been loaded, it returns the fallback.
+func (registry *Registry) Federal() *Jurisdiction {
+	federal, ok := registry.Lookup("US")
+	if !ok {
+		return FallbackFederalJurisdiction
+	}
+
+	return federal
+}
+
+// States returns every state jurisdiction, sorted by code. The jurisdictions must have already been loaded with
+// [Registry.Load].
+func (registry *Registry) States() []*Jurisdiction {
+	return registry.filter(func(jurisdiction *Jurisdiction) bool {
+		return jurisdiction.JurisdictionLevelCode == StateLevelCode
+	})
+}
+
+// Locals returns the local jurisdictions whose parent is the state with the given code, sorted by code. The
+// jurisdictions must have already been loaded with [Registry.Load].
+func (registry *Registry) Locals(stateCode string) []*Jurisdiction {
+	return registry.filter(func(jurisdiction *Jurisdiction) bool {
+		return jurisdiction.JurisdictionLevelCode == LocalLevelCode && jurisdiction.ParentCode == stateCode
+	})
+}
+
+// filter returns the loaded jurisdictions that match, sorted by code.
+func (registry *Registry) filter(match func(jurisdiction *Jurisdiction) bool) []*Jurisdiction {
+	var matched []*Jurisdiction
+
+	for _, jurisdiction := range registry.load().byCode {
+		if match(jurisdiction) {
+			matched = append(matched, jurisdiction)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].JurisdictionCode.Code < matched[j].JurisdictionCode.Code
+	})
+
+	return matched
+}
@@ -0,0 +1,285 @@
+package jurisdiction
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+)
+
+// countingDoer counts the requests sent through it before sending them with the default client.
+type countingDoer struct {
+	requests atomic.Int32
+}
+
+func (doer *countingDoer) Do(req *http.Request) (*http.Response, error) {
+	doer.requests.Add(1)
+
+	return http.DefaultClient.Do(req)
+}
+
+// newLoadedRegistry returns a registry with the jurisdictions loaded from a fake ADP server.
+func newLoadedRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	registry := NewRegistry(&Loader{BaseURL: server.URL})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	return registry
+}
+
+func TestRegistryLoad(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	doer := &countingDoer{}
+	registry := NewRegistry(&Loader{BaseURL: server.URL, Client: doer})
+
+	if registry.Loaded() {
+		t.Fatal("Loaded() = true before loading")
+	}
+
+	if federal := registry.Federal(); federal != FallbackFederalJurisdiction {
+		t.Errorf("Federal() before loading = %+v, want the fallback", federal)
+	}
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := registry.Load(context.Background())
+			if err != nil {
+				t.Errorf("Load() error = %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// The loader and the bundle are each requested once, no matter how many loads happen concurrently.
+	if requests := doer.requests.Load(); requests != 2 {
+		t.Errorf("loader sent %d requests, want 2", requests)
+	}
+
+	if version := registry.PCCVersion(); version != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion() = %q, want %q", version, fakeadp.PCCVersion)
+	}
+
+	california, ok := registry.Lookup("CA")
+	if !ok || california.JurisdictionCode.Name != "California" {
+		t.Errorf("Lookup(CA) = %+v, %t, want California", california, ok)
+	}
+
+	if _, ok := registry.Lookup("ZZ"); ok {
+		t.Error("Lookup(ZZ) found a jurisdiction")
+	}
+}
+
+func TestRegistryLoadFallsBackToEmbeddedSnapshot(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	server.FailNext(http.StatusInternalServerError)
+
+	registry := NewRegistry(&Loader{BaseURL: server.URL})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if _, ok := registry.Lookup("US"); !ok {
+		t.Error("registry does not contain the federal jurisdiction")
+	}
+}
+
+func TestRegistryLoadRetriesAfterEmbeddedSnapshot(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	server.FailNext(http.StatusInternalServerError)
+
+	doer := &countingDoer{}
+	registry := NewRegistry(&Loader{BaseURL: server.URL, Client: doer})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// ADP is not tried again until the retry interval has passed.
+	err = registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if requests := doer.requests.Load(); requests != 1 {
+		t.Errorf("loader sent %d requests before the retry interval passed, want 1", requests)
+	}
+
+	contents := *registry.contents.Load()
+	contents.loadedAt = time.Now().Add(-EmbeddedRetryInterval)
+	registry.contents.Store(&contents)
+
+	err = registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if _, ok := registry.Lookup("CA"); !ok {
+		t.Error("Lookup(CA) after retrying did not find California")
+	}
+}
+
+func TestRegistryRefresh(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	doer := &countingDoer{}
+	registry := NewRegistry(&Loader{BaseURL: server.URL, Client: doer})
+	registry.Replace(&Snapshot{PCCVersion: "2023.1.0", Jurisdictions: []*Jurisdiction{FallbackFederalJurisdiction}})
+
+	server.FailNext(http.StatusInternalServerError)
+
+	changed, err := registry.Refresh(context.Background())
+	if err == nil {
+		t.Fatal("Refresh() error = nil, want an error")
+	}
+
+	if changed {
+		t.Error("Refresh() changed = true after failing")
+	}
+
+	if version := registry.PCCVersion(); version != "2023.1.0" {
+		t.Errorf("PCCVersion() after failed refresh = %q, want the previous version", version)
+	}
+
+	changed, err = registry.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if !changed {
+		t.Error("Refresh() changed = false when the PCC version changed")
+	}
+
+	if version := registry.PCCVersion(); version != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion() after refresh = %q, want %q", version, fakeadp.PCCVersion)
+	}
+
+	if _, ok := registry.Lookup("CA"); !ok {
+		t.Error("Lookup(CA) after refresh did not find California")
+	}
+
+	requests := doer.requests.Load()
+
+	changed, err = registry.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if changed {
+		t.Error("Refresh() changed = true when the PCC version did not change")
+	}
+
+	// Only the loader is requested when the PCC version has not changed.
+	if sent := doer.requests.Load() - requests; sent != 1 {
+		t.Errorf("refresh without a version change sent %d requests, want 1", sent)
+	}
+
+	server.SetPCCVersion("2025.1.0")
+
+	changed, err = registry.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if !changed || registry.PCCVersion() != "2025.1.0" {
+		t.Errorf("Refresh() = %t with version %q, want true with 2025.1.0", changed, registry.PCCVersion())
+	}
+}
+
+func TestRegistryRefreshReplacesEmbeddedSnapshot(t *testing.T) {
+	server := fakeadp.NewServer()
+	defer server.Close()
+
+	server.FailNext(http.StatusInternalServerError)
+
+	registry := NewRegistry(&Loader{BaseURL: server.URL})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	embedded, err := EmbeddedSnapshot()
+	if err != nil {
+		t.Fatalf("EmbeddedSnapshot() error = %v", err)
+	}
+
+	server.SetPCCVersion(embedded.PCCVersion)
+
+	_, err = registry.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// The embedded snapshot may be incomplete, so it is replaced even though the PCC version is the same.
+	if _, ok := registry.Lookup("CA"); !ok {
+		t.Error("Lookup(CA) after refresh did not find California")
+	}
+}
+
+func TestRegistryLocals(t *testing.T) {
+	registry := newLoadedRegistry(t)
+
+	locals := registry.Locals("NY")
+
+	var codes []string
+	for _, local := range locals {
+		codes = append(codes, local.JurisdictionCode.Code)
+	}
+
+	if len(codes) != 2 || codes[0] != "NYC" || codes[1] != "YON" {
+		t.Errorf("Locals(NY) = %v, want [NYC YON]", codes)
+	}
+
+	if locals := registry.Locals("TX"); len(locals) != 0 {
+		t.Errorf("Locals(TX) = %v, want none", locals)
+	}
+}
+
+func TestRegistryStates(t *testing.T) {
+	registry := newLoadedRegistry(t)
+
+	states := registry.States()
+	if len(states) != len(fakeadp.States) {
+		t.Fatalf("States() returned %d states, want %d", len(states), len(fakeadp.States))
+	}
+
+	for i, state := range states {
+		if state.JurisdictionLevelCode != StateLevelCode {
+			t.Errorf("States()[%d] = %+v, want a state", i, state)
+		}
+
+		if i > 0 && states[i-1].JurisdictionCode.Code >= state.JurisdictionCode.Code {
+			t.Errorf("States() is not sorted at %d: %s >= %s",
+				i, states[i-1].JurisdictionCode.Code, state.JurisdictionCode.Code)
+		}
+	}
+}
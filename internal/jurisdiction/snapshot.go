@@ -0,0 +1,84 @@
+package jurisdiction
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+//go:generate go run ../../cmd/taxcalc jurisdictions refresh -o snapshot.json
+
+// embeddedSnapshotJSON is the snapshot of jurisdictions that is embedded in the binary. It is regenerated with
+// `taxcalc jurisdictions refresh`.
+//
+//go:embed snapshot.json
+var embeddedSnapshotJSON []byte
+
+// Snapshot is a set of jurisdictions along with the version of the ADP PCC that they were loaded from.
+type Snapshot struct {
+	PCCVersion    string
+	Jurisdictions []*Jurisdiction
+}
+
+// snapshotJSON is the format of a snapshot when encoded as JSON.
+type snapshotJSON struct {
+	PCCVersion    string                 `json:"pccVersion"`
+	Jurisdictions []snapshotJurisdiction `json:"jurisdictions"`
+}
+
+// snapshotJurisdiction is a jurisdiction when encoded as part of a snapshot. Unlike in requests to the ADP API, the
+// parent code is included.
+type snapshotJurisdiction struct {
+	*Jurisdiction
+	ParentCode string `json:"parentCode,omitempty"`
+}
+
+// EmbeddedSnapshot returns the snapshot of jurisdictions embedded in the binary. It is used as a fallback when the
+// jurisdictions cannot be loaded from ADP and may be out of date.
+func EmbeddedSnapshot() (*Snapshot, error) {
+	snapshot := &Snapshot{}
+
+	err := json.Unmarshal(embeddedSnapshotJSON, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// MarshalJSON encodes the snapshot as JSON, including the parent codes of local jurisdictions.
+func (snapshot *Snapshot) MarshalJSON() ([]byte, error) {
+	encoded := snapshotJSON{
+		PCCVersion:    snapshot.PCCVersion,
+		Jurisdictions: make([]snapshotJurisdiction, len(snapshot.Jurisdictions)),
+	}
+
+	for i, jurisdiction := range snapshot.Jurisdictions {
+		encoded.Jurisdictions[i] = snapshotJurisdiction{Jurisdiction: jurisdiction, ParentCode: jurisdiction.ParentCode}
+	}
+
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a snapshot encoded with [Snapshot.MarshalJSON].
+func (snapshot *Snapshot) UnmarshalJSON(data []byte) error {
+	decoded := snapshotJSON{}
+
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+
+	snapshot.PCCVersion = decoded.PCCVersion
+	snapshot.Jurisdictions = make([]*Jurisdiction, len(decoded.Jurisdictions))
+
+	for i, entry := range decoded.Jurisdictions {
+		if entry.Jurisdiction == nil {
+			entry.Jurisdiction = &Jurisdiction{}
+		}
+
+		entry.Jurisdiction.ParentCode = entry.ParentCode
+		snapshot.Jurisdictions[i] = entry.Jurisdiction
+	}
+
+	return nil
+}
@@ -2,28 +2,52 @@ package request
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/httpclient"
 	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
 	"github.com/tslnc04/tax-calculator/internal/response"
 )
 
+// DefaultTimeout is the timeout for requests made by the default HTTP client when sending requests to the ADP API.
+const DefaultTimeout = httpclient.DefaultTimeout
+
+// Doer sends HTTP requests and returns their responses. It is satisfied by [*http.Client].
+type Doer = httpclient.Doer
+
+// Limiter limits the rate of requests to the ADP API. It is satisfied by [*rate.Limiter].
+type Limiter interface {
+	// Wait blocks until a request may be sent or the context is done, in which case it returns an error.
+	Wait(ctx context.Context) error
+}
+
 // Builder is a builder for the request to the ADP API. The zero value is not sendable and must have at least one salary
 // or hourly income source added before sending.
 type Builder struct {
 	URL              string
+	client           Doer
+	registry         *jurisdiction.Registry
+	retryPolicy      *RetryPolicy
+	limiter          Limiter
 	payFrequencyCode *PayFrequencyCode
-	jurisdictions    []*jurisdiction.Jurisdiction
+	payDate          *time.Time
+	livedIn          []*jurisdiction.Jurisdiction
+	workedIn         []*jurisdiction.Jurisdiction
 	salaries         []BusinessPolicy
 	hourlies         []BusinessPolicy
 	overtime         []PayLine
 	doubletime       []PayLine
-	errorMessage     string
+	payLines         []PayLine
+	statutoryInputs  []StatutoryPolicyInput
+	deductions       []Deduction
+	err              error
 }
 
 // NewBuilder creates a new builder with the given URL. If no URL is given, the [APIURL] is used. Generally, you should
@@ -42,6 +66,62 @@ func NewBuilder(url ...string) *Builder {
 	}
 }
 
+// WithHTTPClient sets the HTTP client used to send the request. If this is not set, a default client with a timeout of
+// [DefaultTimeout] is used. Jurisdictions are loaded by the builder's registry instead, which has its own client.
+func (builder *Builder) WithHTTPClient(client Doer) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting HTTP client")
+
+	builder.client = client
+
+	return builder
+}
+
+// WithRegistry sets the registry that jurisdictions are looked up in by code. It must be set before any jurisdictions
+// are added by code. If this is not set, [jurisdiction.DefaultRegistry] is used.
+func (builder *Builder) WithRegistry(registry *jurisdiction.Registry) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting jurisdiction registry")
+
+	builder.registry = registry
+
+	return builder
+}
+
+// WithRetryPolicy sets how failed requests to the ADP API are retried. If this is not set, [DefaultRetryPolicy] is
+// used.
+func (builder *Builder) WithRetryPolicy(retryPolicy RetryPolicy) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting retry policy to %+v", retryPolicy)
+
+	builder.retryPolicy = &retryPolicy
+
+	return builder
+}
+
+// WithLimiter sets the limiter that is waited on before every attempt to send the request, including retries, so that
+// retrying does not exceed the rate limit. If this is not set, requests are not rate limited.
+func (builder *Builder) WithLimiter(limiter Limiter) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting rate limiter")
+
+	builder.limiter = limiter
+
+	return builder
+}
+
 // WithPayFrequency sets the pay frequency code for the calculation. If this is not set, the default is monthly.
 func (builder *Builder) WithPayFrequency(payFrequencyCode PayFrequencyCode) *Builder {
 	if err := builder.validate(); err != nil {
@@ -50,68 +130,159 @@ func (builder *Builder) WithPayFrequency(payFrequencyCode PayFrequencyCode) *Bui
 
 	glog.V(10).Infof("Setting pay frequency to %s", payFrequencyCode)
 
+	if err := payFrequencyCode.validate(); err != nil {
+		glog.V(10).Infof("Pay frequency is invalid: %s", err)
+
+		builder.err = &ValidationError{Message: err.Error()}
+
+		return builder
+	}
+
 	builder.payFrequencyCode = &payFrequencyCode
 
 	return builder
 }
 
+// WithPayDate sets the date of the paycheck for the calculation, which determines the tax year whose rules are used. If
+// this is not set, the default is today. The date must be in a tax year from [MinTaxYear] through the year after the
+// current one.
+func (builder *Builder) WithPayDate(payDate time.Time) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	maxTaxYear := time.Now().Year() + 1
+	if payDate.Year() < MinTaxYear || payDate.Year() > maxTaxYear {
+		builder.err = &ValidationError{Message: fmt.Sprintf(
+			"pay date %s must be in a tax year from %d to %d", payDate.Format(time.DateOnly), MinTaxYear, maxTaxYear)}
+
+		return builder
+	}
+
+	glog.V(10).Infof("Setting pay date to %s", payDate.Format(time.DateOnly))
+
+	builder.payDate = &payDate
+
+	return builder
+}
+
 // WithJurisdictions adds to both the lived in and worked in jurisdictions for the calculation. If this is not called,
 // the default is just federal.
 func (builder *Builder) WithJurisdictions(jurisdictions ...*jurisdiction.Jurisdiction) *Builder {
+	return builder.WithLivedInJurisdictions(jurisdictions...).WithWorkedInJurisdictions(jurisdictions...)
+}
+
+// WithLivedInJurisdictions adds to the lived in jurisdictions for the calculation. If neither this nor
+// [Builder.WithJurisdictions] is called, the default is just federal.
+func (builder *Builder) WithLivedInJurisdictions(jurisdictions ...*jurisdiction.Jurisdiction) *Builder {
 	if err := builder.validate(); err != nil {
 		return builder
 	}
 
-	glog.V(10).Infof("Adding %d jurisdictions", len(jurisdictions))
+	glog.V(10).Infof("Adding %d lived in jurisdictions", len(jurisdictions))
 
-	builder.jurisdictions = append(builder.jurisdictions, jurisdictions...)
+	builder.livedIn = append(builder.livedIn, jurisdictions...)
 
 	return builder
 }
 
-// WithJurisdictionsByCode adds jurisdictions to the calculation by their codes. This has the side effect of attempting
-// to dynamically load the jurisdictions by code if [jurisdiction.JurisdictionsByCode] is empty. If a code is not found,
-// the builder will not be modified except to signal an error.
-func (builder *Builder) WithJurisdictionsByCode(jurisdictionCodes ...string) *Builder {
+// WithWorkedInJurisdictions adds to the worked in jurisdictions for the calculation. If neither this nor
+// [Builder.WithJurisdictions] is called, the default is just federal.
+func (builder *Builder) WithWorkedInJurisdictions(jurisdictions ...*jurisdiction.Jurisdiction) *Builder {
 	if err := builder.validate(); err != nil {
 		return builder
 	}
 
-	glog.V(10).Infof("Adding %d jurisdictions by code", len(jurisdictionCodes))
+	glog.V(10).Infof("Adding %d worked in jurisdictions", len(jurisdictions))
 
-	if len(jurisdiction.JurisdictionsByCode) < 1 {
-		glog.V(10).Infof("No jurisdictions loaded, attempting to load now")
+	builder.workedIn = append(builder.workedIn, jurisdictions...)
 
-		_, err := jurisdiction.LoadJurisdictions()
-		if err != nil {
-			glog.V(10).Infof("Failed to load jurisdictions: %s", err)
+	return builder
+}
 
-			builder.errorMessage = err.Error()
+// WithJurisdictionsByCode adds jurisdictions to both the lived in and worked in jurisdictions by their codes. This has
+// the side effect of attempting to dynamically load the jurisdictions into the builder's registry if they have not been
+// loaded from ADP, which is abandoned once the context is done. Codes for local jurisdictions also add their parent
+// state. If a code is not found, the builder will not be modified except to signal an error.
+func (builder *Builder) WithJurisdictionsByCode(ctx context.Context, jurisdictionCodes ...string) *Builder {
+	jurisdictions, ok := builder.lookupJurisdictions(ctx, jurisdictionCodes)
+	if !ok {
+		return builder
+	}
 
-			return builder
-		}
+	return builder.WithJurisdictions(jurisdictions...)
+}
+
+// WithLivedInJurisdictionsByCode adds jurisdictions to the lived in jurisdictions by their codes. It has the same side
+// effects as [Builder.WithJurisdictionsByCode].
+func (builder *Builder) WithLivedInJurisdictionsByCode(ctx context.Context, jurisdictionCodes ...string) *Builder {
+	jurisdictions, ok := builder.lookupJurisdictions(ctx, jurisdictionCodes)
+	if !ok {
+		return builder
+	}
+
+	return builder.WithLivedInJurisdictions(jurisdictions...)
+}
+
+// WithWorkedInJurisdictionsByCode adds jurisdictions to the worked in jurisdictions by their codes. It has the same
+// side effects as [Builder.WithJurisdictionsByCode].
+func (builder *Builder) WithWorkedInJurisdictionsByCode(ctx context.Context, jurisdictionCodes ...string) *Builder {
+	jurisdictions, ok := builder.lookupJurisdictions(ctx, jurisdictionCodes)
+	if !ok {
+		return builder
+	}
+
+	return builder.WithWorkedInJurisdictions(jurisdictions...)
+}
+
+// lookupJurisdictions finds the jurisdictions for each of the codes in the builder's registry, loading them if needed.
+// If the builder already has an error or any of the codes cannot be found, this returns false and the
+// builder's error is set.
+func (builder *Builder) lookupJurisdictions(ctx context.Context, jurisdictionCodes []string) (
+	[]*jurisdiction.Jurisdiction, bool,
+) {
+	if err := builder.validate(); err != nil {
+		return nil, false
 	}
 
-	// We store the jurisdictions before appending them to the builder so the builder remains unchanged if this
-	// method sets an error.
-	var jurisdictions []*jurisdiction.Jurisdiction
+	glog.V(10).Infof("Looking up %d jurisdictions by code", len(jurisdictionCodes))
+
+	registry := builder.getRegistry()
+
+	// Loading is a no-op once the jurisdictions have been loaded from ADP, but retries ADP if only the embedded snapshot
+	// could be loaded before.
+	err := registry.Load(ctx)
+	if err != nil {
+		glog.V(10).Infof("Failed to load jurisdictions: %s", err)
+
+		builder.err = err
+
+		return nil, false
+	}
+
+	jurisdictions := make([]*jurisdiction.Jurisdiction, 0, len(jurisdictionCodes))
 
 	for _, code := range jurisdictionCodes {
-		jurisdiction, ok := jurisdiction.JurisdictionsByCode[code]
+		found, ok := registry.Lookup(code)
 		if !ok {
 			glog.V(10).Infof("No jurisdiction found for code: %s", code)
 
-			builder.errorMessage = fmt.Sprintf("no jurisdiction found for code: %s", code)
+			builder.err = &jurisdiction.NotFoundError{Code: code}
 
-			return builder
+			return nil, false
 		}
 
-		jurisdictions = append(jurisdictions, jurisdiction)
-	}
+		jurisdictions = append(jurisdictions, found)
 
-	builder.jurisdictions = append(builder.jurisdictions, jurisdictions...)
+		// Local taxes are withheld alongside the state's, so the parent state is added along with the local.
+		if parent, ok := registry.Lookup(found.ParentCode); ok {
+			glog.V(10).Infof("Adding parent state %s for local jurisdiction %s", found.ParentCode, code)
 
-	return builder
+			jurisdictions = append(jurisdictions, parent)
+		}
+	}
+
+	return jurisdictions, true
 }
 
 // WithSalary adds a salary to the income sources. The amount is in dollars and per the frequency.
@@ -125,7 +296,7 @@ func (builder *Builder) WithSalary(amount float64, frequency SalaryFrequency) *B
 	if amount < 0 {
 		glog.V(10).Infof("Salary amount is negative")
 
-		builder.errorMessage = "salary amount must be non-negative"
+		builder.err = &ValidationError{Message: "salary amount must be non-negative"}
 
 		return builder
 	}
@@ -133,7 +304,7 @@ func (builder *Builder) WithSalary(amount float64, frequency SalaryFrequency) *B
 	if err := frequency.validate(); err != nil {
 		glog.V(10).Infof("Salary frequency is invalid: %s", err)
 
-		builder.errorMessage = err.Error()
+		builder.err = &ValidationError{Message: err.Error()}
 
 		return builder
 	}
@@ -155,7 +326,7 @@ func (builder *Builder) WithHourly(hours, rate float64) *Builder {
 	if hours < 0 {
 		glog.V(10).Infof("Hourly hours is negative: %.2f", hours)
 
-		builder.errorMessage = "hourly hours must be non-negative"
+		builder.err = &ValidationError{Message: "hourly hours must be non-negative"}
 
 		return builder
 	}
@@ -163,7 +334,7 @@ func (builder *Builder) WithHourly(hours, rate float64) *Builder {
 	if rate < 0 {
 		glog.V(10).Infof("Hourly rate is negative: %.2f", rate)
 
-		builder.errorMessage = "hourly rate must be non-negative"
+		builder.err = &ValidationError{Message: "hourly rate must be non-negative"}
 
 		return builder
 	}
@@ -185,7 +356,7 @@ func (builder *Builder) WithOvertime(hours, rate float64) *Builder {
 	if hours < 0 {
 		glog.V(10).Infof("Overtime hours is negative: %.2f", hours)
 
-		builder.errorMessage = "overtime hours must be non-negative"
+		builder.err = &ValidationError{Message: "overtime hours must be non-negative"}
 
 		return builder
 	}
@@ -193,7 +364,7 @@ func (builder *Builder) WithOvertime(hours, rate float64) *Builder {
 	if rate < 0 {
 		glog.V(10).Infof("Overtime rate is negative: %.2f", rate)
 
-		builder.errorMessage = "overtime rate must be non-negative"
+		builder.err = &ValidationError{Message: "overtime rate must be non-negative"}
 
 		return builder
 	}
@@ -215,7 +386,7 @@ func (builder *Builder) WithDoubleTime(hours, rate float64) *Builder {
 	if hours < 0 {
 		glog.V(10).Infof("Double time hours is negative: %.2f", hours)
 
-		builder.errorMessage = "double time hours must be non-negative"
+		builder.err = &ValidationError{Message: "double time hours must be non-negative"}
 
 		return builder
 	}
@@ -223,7 +394,7 @@ func (builder *Builder) WithDoubleTime(hours, rate float64) *Builder {
 	if rate < 0 {
 		glog.V(10).Infof("Double time rate is negative: %.2f", rate)
 
-		builder.errorMessage = "double time rate must be non-negative"
+		builder.err = &ValidationError{Message: "double time rate must be non-negative"}
 
 		return builder
 	}
@@ -234,22 +405,294 @@ func (builder *Builder) WithDoubleTime(hours, rate float64) *Builder {
 	return builder
 }
 
-// HandleError consumes the error message and returns it as an error. If there is no error message, this returns nil.
-// The builder is guaranteed to be in a valid (but not necessarily sendable) state after this.
-func (builder *Builder) HandleError() error {
-	if builder.errorMessage == "" {
-		return nil
+// WithPayLine adds a pay line to the additional earnings. It is the general form of [Builder.WithOvertime],
+// [Builder.WithDoubleTime], and [Builder.WithSupplementalPay] for earnings that they do not cover.
+func (builder *Builder) WithPayLine(payLine PayLine) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Adding %s pay line of %.2f for %s units", payLine.Name.Value, payLine.Amount.Value,
+		payLine.Unit.Value)
+
+	units, err := strconv.ParseFloat(payLine.Unit.Value, 64)
+	if err != nil || units < 0 {
+		glog.V(10).Infof("Pay line units are invalid: %s", payLine.Unit.Value)
+
+		builder.err = &ValidationError{Message: "pay line units must be a non-negative number"}
+
+		return builder
+	}
+
+	if payLine.Amount.Value < 0 || payLine.ClientFactor.Value < 0 {
+		glog.V(10).Infof("Pay line amount or client factor is negative: %+v", payLine)
+
+		builder.err = &ValidationError{Message: "pay line amount and client factor must be non-negative"}
+
+		return builder
+	}
+
+	if payLine.WithholdingMethod != nil {
+		if err := payLine.WithholdingMethod.validate(); err != nil {
+			glog.V(10).Infof("Pay line withholding method is invalid: %s", err)
+
+			builder.err = &ValidationError{Message: err.Error()}
+
+			return builder
+		}
+	}
+
+	builder.payLines = append(builder.payLines, payLine)
+
+	return builder
+}
+
+// WithSupplementalPay adds supplemental pay of the earning type, which must be one of [BonusEarningType],
+// [CommissionEarningType], [TipsEarningType], or [OtherSupplementalEarningType]. The amount is in dollars for the pay
+// period, and federal income tax is withheld from it with the method.
+func (builder *Builder) WithSupplementalPay(
+	earningType EarningType, amount float64, method SupplementalWithholdingMethod,
+) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	if _, ok := supplementalPayLineNames[earningType]; !ok {
+		glog.V(10).Infof("Earning type is not supplemental: %+v", earningType)
+
+		builder.err = &ValidationError{Message: "invalid supplemental earning type: " + earningType.Value}
+
+		return builder
 	}
 
-	err := fmt.Errorf(builder.errorMessage)
-	builder.errorMessage = ""
+	if amount < 0 {
+		glog.V(10).Infof("Supplemental pay amount is negative: %.2f", amount)
+
+		builder.err = &ValidationError{Message: "supplemental pay amount must be non-negative"}
+
+		return builder
+	}
+
+	return builder.WithPayLine(newSupplementalPayLine(earningType, amount, method))
+}
+
+// WithBonus adds a bonus to the pay period. It is a convenience for calling [Builder.WithSupplementalPay] with
+// [BonusEarningType].
+func (builder *Builder) WithBonus(amount float64, method SupplementalWithholdingMethod) *Builder {
+	return builder.WithSupplementalPay(BonusEarningType, amount, method)
+}
+
+// WithSupplementalPaySpecs adds each of the supplemental pay to the calculation. It is a convenience for calling
+// [Builder.WithSupplementalPay] with parsed [SupplementalPaySpec] values.
+func (builder *Builder) WithSupplementalPaySpecs(specs ...SupplementalPaySpec) *Builder {
+	for _, spec := range specs {
+		builder.WithSupplementalPay(spec.EarningType, spec.Amount, spec.Method)
+	}
+
+	return builder
+}
+
+// WithFilingStatus sets the federal filing status from step 1(c) of the W4. If this is not set, ADP's default filing
+// status is used.
+func (builder *Builder) WithFilingStatus(filingStatus FilingStatus) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting filing status to %s", filingStatus)
+
+	if err := filingStatus.validate(); err != nil {
+		glog.V(10).Infof("Filing status is invalid: %s", err)
+
+		builder.err = &ValidationError{Message: err.Error()}
+
+		return builder
+	}
+
+	builder.setStatutoryInput(newFilingStatusPolicyInput(filingStatus))
+
+	return builder
+}
+
+// WithMultipleJobs sets whether the checkbox in step 2(c) of the W4 is checked, meaning there are multiple jobs or the
+// spouse also works.
+func (builder *Builder) WithMultipleJobs(multipleJobs bool) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting multiple jobs to %t", multipleJobs)
+
+	builder.setStatutoryInput(newMultipleJobsPolicyInput(multipleJobs))
+
+	return builder
+}
+
+// WithDependentsAmount sets the total amount of the dependents credit from step 3 of the W4. The amount is in dollars
+// per year.
+func (builder *Builder) WithDependentsAmount(amount float64) *Builder {
+	return builder.withW4Amount("dependents amount", newDependentsPolicyInput(amount), amount)
+}
+
+// WithOtherIncome sets the other income not from jobs from step 4(a) of the W4. The amount is in dollars per year.
+func (builder *Builder) WithOtherIncome(amount float64) *Builder {
+	return builder.withW4Amount("other income", newOtherIncomePolicyInput(amount), amount)
+}
+
+// WithW4Deductions sets the deductions other than the standard deduction from step 4(b) of the W4. The amount is in
+// dollars per year. This is unrelated to the payroll deductions taken out of each paycheck.
+func (builder *Builder) WithW4Deductions(amount float64) *Builder {
+	return builder.withW4Amount("W4 deductions", newDeductionsPolicyInput(amount), amount)
+}
+
+// WithExtraWithholding sets the extra withholding from step 4(c) of the W4. The amount is in dollars per pay period.
+func (builder *Builder) WithExtraWithholding(amount float64) *Builder {
+	return builder.withW4Amount("extra withholding", newExtraWithholdingPolicyInput(amount), amount)
+}
+
+// withW4Amount sets a statutory policy input for an amount on the W4, ensuring the amount is non-negative. The name is
+// only used for logging and error messages.
+func (builder *Builder) withW4Amount(name string, input StatutoryPolicyInput, amount float64) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Setting %s to %.2f", name, amount)
+
+	if amount < 0 {
+		glog.V(10).Infof("W4 amount for %s is negative: %.2f", name, amount)
+
+		builder.err = &ValidationError{Message: name + " must be non-negative"}
+
+		return builder
+	}
+
+	builder.setStatutoryInput(input)
+
+	return builder
+}
+
+// setStatutoryInput adds the statutory policy input to the builder, replacing any existing input with the same ID.
+func (builder *Builder) setStatutoryInput(input StatutoryPolicyInput) {
+	for i, existing := range builder.statutoryInputs {
+		if existing.ID == input.ID {
+			builder.statutoryInputs[i] = input
+
+			return
+		}
+	}
+
+	builder.statutoryInputs = append(builder.statutoryInputs, input)
+}
+
+// WithDeduction adds a deduction to the calculation. If the amount type is [PercentDeductionAmountType], the amount is
+// a percent of the gross pay from 0 to 100. Otherwise, it is in dollars per pay period.
+func (builder *Builder) WithDeduction(
+	deductionType DeductionType, amountType DeductionAmountType, amount float64,
+) *Builder {
+	if err := builder.validate(); err != nil {
+		return builder
+	}
+
+	glog.V(10).Infof("Adding %s deduction of %.2f with amount type %s", deductionType, amount, amountType.Code)
+
+	if err := deductionType.validate(); err != nil {
+		glog.V(10).Infof("Deduction type is invalid: %s", err)
+
+		builder.err = &ValidationError{Message: err.Error()}
+
+		return builder
+	}
+
+	if err := amountType.validate(); err != nil {
+		glog.V(10).Infof("Deduction amount type is invalid: %s", err)
+
+		builder.err = &ValidationError{Message: err.Error()}
+
+		return builder
+	}
+
+	if amount < 0 {
+		glog.V(10).Infof("Deduction amount is negative: %.2f", amount)
+
+		builder.err = &ValidationError{Message: "deduction amount must be non-negative"}
+
+		return builder
+	}
+
+	if amountType == PercentDeductionAmountType && amount > 100 {
+		glog.V(10).Infof("Deduction percent is greater than 100: %.2f", amount)
+
+		builder.err = &ValidationError{Message: "deduction percent must be at most 100"}
+
+		return builder
+	}
+
+	deduction := newDeduction(deductionType, amountType, amount, len(builder.deductions)+1)
+	builder.deductions = append(builder.deductions, deduction)
+
+	return builder
+}
+
+// WithDeductionSpecs adds each of the deductions to the calculation. It is a convenience for calling
+// [Builder.WithDeduction] with parsed [DeductionSpec] values.
+func (builder *Builder) WithDeductionSpecs(specs ...DeductionSpec) *Builder {
+	for _, spec := range specs {
+		builder.WithDeduction(spec.DeductionType, spec.AmountType, spec.Amount)
+	}
+
+	return builder
+}
+
+// HandleError consumes the error and returns it. If there is no error, this returns nil. The builder is guaranteed to
+// be in a valid (but not necessarily sendable) state after this.
+func (builder *Builder) HandleError() error {
+	err := builder.err
+	builder.err = nil
 
 	return err
 }
 
+// Build returns the request that would be sent to the ADP API. This does not modify the builder. If there is an error
+// validating the request, this returns an error.
+func (builder *Builder) Build() (*Request, error) {
+	if err := builder.validate(); err != nil {
+		return nil, err
+	}
+
+	return builder.buildRequest(), nil
+}
+
 // Send sends the request to the ADP API and returns a parsed [response.Response]. This does not modify the builder. If
 // there is an error validating or sending the request, this returns an error.
 func (builder *Builder) Send() (*response.Response, error) {
+	return builder.SendContext(context.Background())
+}
+
+// SendContext is like [Builder.Send] but uses the context for the request to the ADP API, so it is abandoned once the
+// context is done. Transient failures such as server errors and rate limiting are retried according to the builder's
+// [RetryPolicy], waiting on its [Limiter] before every attempt.
+func (builder *Builder) SendContext(ctx context.Context) (*response.Response, error) {
+	body, err := builder.SendRawContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &response.Response{}
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		glog.V(10).Infof("Failed to JSON unmarshal ADP API response: %s", err)
+
+		return nil, &DecodeError{Err: err}
+	}
+
+	return response, nil
+}
+
+// SendRawContext is like [Builder.SendContext] but returns the body of the response from the ADP API without decoding
+// it. This is useful for checking whether the format of the response has changed.
+func (builder *Builder) SendRawContext(ctx context.Context) ([]byte, error) {
 	if err := builder.validate(); err != nil {
 		return nil, err
 	}
@@ -263,7 +706,68 @@ func (builder *Builder) Send() (*response.Response, error) {
 		return nil, err
 	}
 
-	resp, err := http.Post(builder.URL, "application/json", bytes.NewBuffer(requestJSON))
+	client := builder.client
+	if client == nil {
+		client = httpclient.Default
+	}
+
+	retryPolicy := DefaultRetryPolicy
+	if builder.retryPolicy != nil {
+		retryPolicy = *builder.retryPolicy
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = builder.waitForLimiter(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := builder.sendOnce(ctx, client, requestJSON)
+		if err == nil || attempt >= retryPolicy.MaxAttempts || !isRetryable(ctx, err) {
+			return body, err
+		}
+
+		delay := retryPolicy.delay(attempt, err)
+
+		glog.V(10).Infof("Attempt %d sending request to ADP API failed, retrying in %s: %s", attempt, delay, err)
+
+		err = sleepContext(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForLimiter waits until the builder's limiter allows a request to be sent. It returns immediately if the builder
+// has no limiter.
+func (builder *Builder) waitForLimiter(ctx context.Context) error {
+	if builder.limiter == nil {
+		return nil
+	}
+
+	err := builder.limiter.Wait(ctx)
+	if err != nil {
+		glog.V(10).Infof("Failed to wait for rate limit: %s", err)
+
+		return fmt.Errorf("failed to wait for rate limit: %w", err)
+	}
+
+	return nil
+}
+
+// sendOnce sends the JSON encoded request to the ADP API a single time and returns the body of the response. Statuses
+// other than OK are returned as an [UpstreamError] or [RateLimitedError].
+func (builder *Builder) sendOnce(ctx context.Context, client Doer, requestJSON []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, builder.URL, bytes.NewReader(requestJSON))
+	if err != nil {
+		glog.V(10).Infof("Failed to create request to ADP API: %s", err)
+
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
 	if err != nil {
 		glog.V(10).Infof("Failed to send request to ADP API: %s", err)
 
@@ -272,10 +776,16 @@ func (builder *Builder) Send() (*response.Response, error) {
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		glog.V(10).Infof("Rate limited sending request to ADP API: %s", resp.Status)
+
+		return nil, &RateLimitedError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		glog.V(10).Infof("Status was not OK sending request to ADP API: %s", resp.Status)
 
-		return nil, fmt.Errorf("status was not OK sending request: %s", resp.Status)
+		return nil, &UpstreamError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -285,16 +795,7 @@ func (builder *Builder) Send() (*response.Response, error) {
 		return nil, err
 	}
 
-	response := &response.Response{}
-
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		glog.V(10).Infof("Failed to JSON unmarshal ADP API response: %s", err)
-
-		return nil, err
-	}
-
-	return response, nil
+	return body, nil
 }
 
 // buildRequest builds the request to the ADP API. This is called by [Send] and should not be called directly. It
@@ -309,53 +810,86 @@ func (builder *Builder) buildRequest() *Request {
 		payFrequency = &MonthlyPayFrequencyCode
 	}
 
-	jurisdictions := append([]*jurisdiction.Jurisdiction{}, builder.jurisdictions...)
-	hasFederal := false
-
-	for _, jurisdiction := range jurisdictions {
-		if jurisdiction.JurisdictionCode.Code == "US" {
-			hasFederal = true
-
-			break
-		}
+	payDate := time.Now()
+	if builder.payDate != nil {
+		payDate = *builder.payDate
 	}
 
-	if !hasFederal {
-		jurisdictions = append(jurisdictions, jurisdiction.GetFederalJurisdiction())
-	}
+	federal := builder.getRegistry().Federal()
+	livedIn := withFederalJurisdiction(builder.livedIn, federal)
+	workedIn := withFederalJurisdiction(builder.workedIn, federal)
 
 	// Copy the slices and join them so that the builder remains unmodified.
-	policies := make([]BusinessPolicy, len(builder.salaries))
+	policies := make([]BusinessPolicy, len(builder.salaries)+len(builder.hourlies))
 	copy(policies, builder.salaries)
 	copy(policies[len(builder.salaries):], builder.hourlies)
 
-	payLines := make([]PayLine, len(builder.overtime)+len(builder.doubletime))
-	copy(payLines, builder.overtime)
-	copy(payLines[len(builder.overtime):], builder.doubletime)
+	payLines := make([]PayLine, 0, len(builder.overtime)+len(builder.doubletime)+len(builder.payLines))
+	payLines = append(payLines, builder.overtime...)
+	payLines = append(payLines, builder.doubletime...)
+	payLines = append(payLines, builder.payLines...)
+
+	statutoryInputs := make([]StatutoryPolicyInput, 1, len(builder.statutoryInputs)+1)
+	statutoryInputs[0] = StatutoryPolicy2020W4
+	statutoryInputs = append(statutoryInputs, builder.statutoryInputs...)
 
 	request := &Request{
 		CalculationTypeCode:   GrossToNetTypeCode,
-		StatutoryPolicyInputs: []StatutoryPolicyInput{StatutoryPolicy2020W4},
+		StatutoryPolicyInputs: statutoryInputs,
 		Jurisdictions: Jurisdictions{
-			LivedInJurisdictions:  jurisdictions,
-			WorkedInJurisdictions: jurisdictions,
+			LivedInJurisdictions:  livedIn,
+			WorkedInJurisdictions: workedIn,
 		},
-		PayDate:            time.Now().Format(time.DateOnly),
+		PayDate:            payDate.Format(time.DateOnly),
 		PayFrequencyCode:   *payFrequency,
 		BusinessPolicies:   policies,
 		AdditionalEarnings: AdditionalEarnings{PayLines: payLines},
-		Deductions:         []struct{}{},
+		Deductions:         append([]Deduction{}, builder.deductions...),
 	}
 
 	return request
 }
 
-// validate ensures that the builder is in a valid state. If there is an error message, it is returned. Otherwise, nil
-// is returned. This does not guarantee that the builder is sendable nor is it guaranteed to be valid after this.
-func (builder *Builder) validate() error {
-	if builder.errorMessage != "" {
-		return fmt.Errorf(builder.errorMessage)
+// withFederalJurisdiction returns a copy of the jurisdictions without duplicates and with the federal jurisdiction
+// appended if it is not already present.
+func withFederalJurisdiction(
+	jurisdictions []*jurisdiction.Jurisdiction, federal *jurisdiction.Jurisdiction,
+) []*jurisdiction.Jurisdiction {
+	deduplicated := make([]*jurisdiction.Jurisdiction, 0, len(jurisdictions)+1)
+	seen := make(map[string]bool, len(jurisdictions))
+	hasFederal := false
+
+	for _, jurisdiction := range jurisdictions {
+		if seen[jurisdiction.JurisdictionID] {
+			continue
+		}
+
+		seen[jurisdiction.JurisdictionID] = true
+		deduplicated = append(deduplicated, jurisdiction)
+
+		if jurisdiction.JurisdictionCode.Code == "US" {
+			hasFederal = true
+		}
 	}
 
-	return nil
+	if !hasFederal {
+		deduplicated = append(deduplicated, federal)
+	}
+
+	return deduplicated
+}
+
+// getRegistry returns the builder's registry, or [jurisdiction.DefaultRegistry] if it does not have one.
+func (builder *Builder) getRegistry() *jurisdiction.Registry {
+	if builder.registry == nil {
+		return jurisdiction.DefaultRegistry
+	}
+
+	return builder.registry
+}
+
+// validate ensures that the builder is in a valid state. If there is an error, it is returned. Otherwise, nil is
+// returned. This does not guarantee that the builder is sendable nor is it guaranteed to be valid after this.
+func (builder *Builder) validate() error {
+	return builder.err
 }
@@ -0,0 +1,552 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+)
+
+// fastRetryPolicy retries like the default policy but without waiting, so tests of retries are quick.
+var fastRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+// newTestServer starts a fake ADP server and returns it along with a registry of its jurisdictions. The server is
+// closed once the test is done.
+func newTestServer(t *testing.T) (*fakeadp.Server, *jurisdiction.Registry) {
+	t.Helper()
+
+	server := fakeadp.NewServer()
+	t.Cleanup(server.Close)
+
+	registry := jurisdiction.NewRegistry(&jurisdiction.Loader{BaseURL: server.URL})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load jurisdictions from fake server: %s", err)
+	}
+
+	return server, registry
+}
+
+func TestSendContext(t *testing.T) {
+	ctx := context.Background()
+
+	server, registry := newTestServer(t)
+
+	response, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+		WithSalary(120000, AnnualSalaryFrequency).
+		WithPayFrequency(MonthlyPayFrequencyCode).
+		WithJurisdictionsByCode(ctx, "CA").
+		SendContext(context.Background())
+	if err != nil {
+		t.Fatalf("SendContext() error = %v", err)
+	}
+
+	// The fake server withholds 10% federal income tax, 6.2% social security, 1.45% Medicare, and 8% for California.
+	if response.Gross.Amount != 10000 {
+		t.Errorf("Gross = %.2f, want 10000.00", response.Gross.Amount)
+	}
+
+	if response.Net.Amount != 7435 {
+		t.Errorf("Net = %.2f, want 7435.00", response.Net.Amount)
+	}
+
+	if len(response.Taxes.State.Entities) != 1 || response.Taxes.State.Entities[0].Amount != 800 {
+		t.Errorf("State taxes = %+v, want a single entity of 800.00", response.Taxes.State.Entities)
+	}
+}
+
+func TestBuilderValidationErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(builder *Builder) *Builder
+	}{
+		{"negative salary", func(builder *Builder) *Builder {
+			return builder.WithSalary(-1, AnnualSalaryFrequency)
+		}},
+		{"invalid salary frequency", func(builder *Builder) *Builder {
+			return builder.WithSalary(1, SalaryFrequency("weekly"))
+		}},
+		{"negative hours", func(builder *Builder) *Builder {
+			return builder.WithHourly(-1, 20)
+		}},
+		{"invalid pay frequency", func(builder *Builder) *Builder {
+			return builder.WithPayFrequency(PayFrequencyCode{})
+		}},
+		{"invalid filing status", func(builder *Builder) *Builder {
+			return builder.WithFilingStatus(FilingStatus{Code: "WIDOWED"})
+		}},
+		{"negative extra withholding", func(builder *Builder) *Builder {
+			return builder.WithExtraWithholding(-5)
+		}},
+		{"deduction percent over 100", func(builder *Builder) *Builder {
+			return builder.WithDeduction(Traditional401kDeductionType, PercentDeductionAmountType, 101)
+		}},
+		{"pay date before min tax year", func(builder *Builder) *Builder {
+			return builder.WithPayDate(time.Date(MinTaxYear-1, time.December, 31, 0, 0, 0, 0, time.UTC))
+		}},
+		{"pay date after next year", func(builder *Builder) *Builder {
+			return builder.WithPayDate(time.Now().AddDate(2, 0, 0))
+		}},
+		{"non-supplemental earning type", func(builder *Builder) *Builder {
+			return builder.WithSupplementalPay(EarningType{Value: "Salary"}, 100, FlatSupplementalWithholdingMethod)
+		}},
+		{"negative bonus", func(builder *Builder) *Builder {
+			return builder.WithBonus(-100, FlatSupplementalWithholdingMethod)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := test.build(NewBuilder())
+
+			_, err := builder.Send()
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Send() error = %v, want a ValidationError", err)
+			}
+
+			if err := builder.HandleError(); err == nil {
+				t.Error("HandleError() = nil, want the validation error")
+			}
+
+			if err := builder.HandleError(); err != nil {
+				t.Errorf("HandleError() after handling = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestWithPayDate(t *testing.T) {
+	request, err := NewBuilder().
+		WithSalary(50000, AnnualSalaryFrequency).
+		WithPayDate(time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if request.PayDate != "2023-03-15" {
+		t.Errorf("PayDate = %q, want %q", request.PayDate, "2023-03-15")
+	}
+
+	request, err = NewBuilder().WithSalary(50000, AnnualSalaryFrequency).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if today := time.Now().Format(time.DateOnly); request.PayDate != today {
+		t.Errorf("default PayDate = %q, want today %q", request.PayDate, today)
+	}
+}
+
+func TestWithJurisdictionsByCodeNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	_, registry := newTestServer(t)
+
+	builder := NewBuilder().
+		WithRegistry(registry).
+		WithSalary(50000, AnnualSalaryFrequency).
+		WithJurisdictionsByCode(ctx, "CA", "ZZ")
+
+	var notFoundErr *jurisdiction.NotFoundError
+	if err := builder.HandleError(); !errors.As(err, &notFoundErr) || notFoundErr.Code != "ZZ" {
+		t.Fatalf("HandleError() = %v, want NotFoundError for ZZ", err)
+	}
+
+	if len(builder.buildRequest().Jurisdictions.LivedInJurisdictions) != 1 {
+		t.Error("builder was modified by WithJurisdictionsByCode despite the error")
+	}
+}
+
+// jurisdictionCodes returns the codes of the jurisdictions in order.
+func jurisdictionCodes(jurisdictions []*jurisdiction.Jurisdiction) []string {
+	codes := make([]string, len(jurisdictions))
+	for i, jurisdiction := range jurisdictions {
+		codes[i] = jurisdiction.JurisdictionCode.Code
+	}
+
+	return codes
+}
+
+func equalCodes(got []*jurisdiction.Jurisdiction, want ...string) bool {
+	codes := jurisdictionCodes(got)
+	if len(codes) != len(want) {
+		return false
+	}
+
+	for i := range codes {
+		if codes[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestBuildRequestJurisdictions(t *testing.T) {
+	ctx := context.Background()
+
+	_, registry := newTestServer(t)
+
+	tests := []struct {
+		name         string
+		build        func(builder *Builder) *Builder
+		wantLivedIn  []string
+		wantWorkedIn []string
+	}{
+		{
+			name:         "federal by default",
+			build:        func(builder *Builder) *Builder { return builder },
+			wantLivedIn:  []string{"US"},
+			wantWorkedIn: []string{"US"},
+		},
+		{
+			name:         "both",
+			build:        func(builder *Builder) *Builder { return builder.WithJurisdictionsByCode(ctx, "CO") },
+			wantLivedIn:  []string{"CO", "US"},
+			wantWorkedIn: []string{"CO", "US"},
+		},
+		{
+			name: "lived in and worked in",
+			build: func(builder *Builder) *Builder {
+				return builder.WithLivedInJurisdictionsByCode(ctx, "NJ").WithWorkedInJurisdictionsByCode(ctx, "NY")
+			},
+			wantLivedIn:  []string{"NJ", "US"},
+			wantWorkedIn: []string{"NY", "US"},
+		},
+		{
+			name: "local adds parent state once",
+			build: func(builder *Builder) *Builder {
+				return builder.WithJurisdictionsByCode(ctx, "NY").WithJurisdictionsByCode(ctx, "NYC")
+			},
+			wantLivedIn:  []string{"NY", "NYC", "US"},
+			wantWorkedIn: []string{"NY", "NYC", "US"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := test.build(NewBuilder().WithRegistry(registry).WithSalary(50000, AnnualSalaryFrequency))
+			if err := builder.HandleError(); err != nil {
+				t.Fatalf("HandleError() = %v", err)
+			}
+
+			request := builder.buildRequest()
+
+			if !equalCodes(request.Jurisdictions.LivedInJurisdictions, test.wantLivedIn...) {
+				t.Errorf("LivedInJurisdictions = %v, want %v",
+					jurisdictionCodes(request.Jurisdictions.LivedInJurisdictions), test.wantLivedIn)
+			}
+
+			if !equalCodes(request.Jurisdictions.WorkedInJurisdictions, test.wantWorkedIn...) {
+				t.Errorf("WorkedInJurisdictions = %v, want %v",
+					jurisdictionCodes(request.Jurisdictions.WorkedInJurisdictions), test.wantWorkedIn)
+			}
+		})
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []int
+		wantCalls int
+		wantErr   any
+	}{
+		{
+			name:      "server error is retried",
+			failures:  []int{http.StatusServiceUnavailable},
+			wantCalls: 2,
+		},
+		{
+			name:      "rate limit is retried",
+			failures:  []int{http.StatusTooManyRequests, http.StatusBadGateway},
+			wantCalls: 3,
+		},
+		{
+			name:      "client error is not retried",
+			failures:  []int{http.StatusBadRequest},
+			wantCalls: 1,
+			wantErr:   new(*UpstreamError),
+		},
+		{
+			name:      "gives up after max attempts",
+			failures:  []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests},
+			wantCalls: 3,
+			wantErr:   new(*RateLimitedError),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, registry := newTestServer(t)
+			server.FailNext(test.failures...)
+
+			_, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+				WithSalary(50000, AnnualSalaryFrequency).
+				WithRetryPolicy(fastRetryPolicy).
+				Send()
+
+			if test.wantErr == nil && err != nil {
+				t.Errorf("Send() error = %v, want nil", err)
+			}
+
+			if test.wantErr != nil && !errors.As(err, test.wantErr) {
+				t.Errorf("Send() error = %v, want %T", err, test.wantErr)
+			}
+
+			if calls := server.CalculationCount(); calls != test.wantCalls {
+				t.Errorf("server received %d calls, want %d", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+// countingLimiter is a [Limiter] that never waits and counts how many times it was waited on.
+type countingLimiter struct {
+	waits int
+}
+
+func (limiter *countingLimiter) Wait(context.Context) error {
+	limiter.waits++
+
+	return nil
+}
+
+func TestSendWaitsForLimiterBeforeEveryAttempt(t *testing.T) {
+	server, registry := newTestServer(t)
+	server.FailNext(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	limiter := &countingLimiter{}
+
+	_, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+		WithSalary(50000, AnnualSalaryFrequency).
+		WithRetryPolicy(fastRetryPolicy).
+		WithLimiter(limiter).
+		Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if limiter.waits != 3 {
+		t.Errorf("limiter was waited on %d times, want 3", limiter.waits)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "timeout",
+			err:  &url.Error{Op: "Post", URL: APIURL, Err: os.ErrDeadlineExceeded},
+			want: true,
+		},
+		{
+			name: "connection refused",
+			err:  &url.Error{Op: "Post", URL: APIURL, Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}},
+			want: true,
+		},
+		{
+			name: "temporary DNS failure",
+			err:  &url.Error{Op: "Post", URL: APIURL, Err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}},
+			want: true,
+		},
+		{
+			name: "host not found",
+			err:  &url.Error{Op: "Post", URL: APIURL, Err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+			want: false,
+		},
+		{
+			name: "other network error",
+			err:  &url.Error{Op: "Post", URL: APIURL, Err: &net.OpError{Op: "dial", Err: syscall.ENETUNREACH}},
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRetryable(context.Background(), test.err); got != test.want {
+				t.Errorf("isRetryable(%v) = %t, want %t", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSendContextCanceled(t *testing.T) {
+	server, registry := newTestServer(t)
+	server.FailNext(http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewBuilder(server.APIURL()).WithRegistry(registry).WithSalary(50000, AnnualSalaryFrequency).SendContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("SendContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestDeductions(t *testing.T) {
+	server, registry := newTestServer(t)
+
+	response, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+		WithSalary(120000, AnnualSalaryFrequency).
+		WithDeduction(Traditional401kDeductionType, PercentDeductionAmountType, 10).
+		WithDeduction(GarnishmentDeductionType, FlatDeductionAmountType, 100).
+		Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(response.Deductions.Entities) != 2 || response.Deductions.SummaryEntity.Amount != 1100 {
+		t.Errorf("Deductions = %+v, want 1000.00 401(k) and 100.00 garnishment", response.Deductions)
+	}
+
+	// Only the pre-tax 401(k) reduces the 9000.00 taxable income, which is taxed at 17.65% federally.
+	if response.Net.Amount != 7311.5 {
+		t.Errorf("Net = %.2f, want 7311.50", response.Net.Amount)
+	}
+}
+
+func TestParseDeductionSpec(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    DeductionSpec
+		wantErr bool
+	}{
+		{value: "401k=6%", want: DeductionSpec{Traditional401kDeductionType, PercentDeductionAmountType, 6}},
+		{value: "hsa=125.50", want: DeductionSpec{HSADeductionType, FlatDeductionAmountType, 125.5}},
+		{value: "garnishment=0", want: DeductionSpec{GarnishmentDeductionType, FlatDeductionAmountType, 0}},
+		{value: "pension=5", wantErr: true},
+		{value: "401k", wantErr: true},
+		{value: "401k=five", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseDeductionSpec(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseDeductionSpec() error = %v, wantErr %t", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("ParseDeductionSpec() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHourlyPay(t *testing.T) {
+	server, registry := newTestServer(t)
+
+	response, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+		WithSalary(1000, PeriodicSalaryFrequency).
+		WithHourly(80, 30).
+		WithOvertime(10, 30).
+		WithDoubleTime(2, 30).
+		Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	// The salary of 1000.00 is followed by 2400.00 of regular pay, 450.00 of overtime, and 120.00 of double time.
+	wantEarnings := []float64{1000, 2400, 450, 120}
+
+	if len(response.Earnings.Entities) != len(wantEarnings) {
+		t.Fatalf("Earnings = %+v, want %v", response.Earnings.Entities, wantEarnings)
+	}
+
+	for i, earning := range response.Earnings.Entities {
+		if earning.Amount != wantEarnings[i] {
+			t.Errorf("Earnings[%d] = %.2f, want %.2f", i, earning.Amount, wantEarnings[i])
+		}
+	}
+
+	if response.Gross.Amount != 3970 {
+		t.Errorf("Gross = %.2f, want 3970.00", response.Gross.Amount)
+	}
+}
+
+func TestSupplementalPay(t *testing.T) {
+	ctx := context.Background()
+
+	server, registry := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		method  SupplementalWithholdingMethod
+		wantNet float64
+	}{
+		// The 5000.00 bonus is withheld at the 22% flat rate, separately from the 10000.00 salary.
+		{"flat", FlatSupplementalWithholdingMethod, 10552.5},
+		// The bonus is added to the salary, and the 15000.00 total is withheld at the regular rate.
+		{"aggregate", AggregateSupplementalWithholdingMethod, 11152.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+				WithSalary(120000, AnnualSalaryFrequency).
+				WithJurisdictionsByCode(ctx, "CA").
+				WithBonus(5000, test.method).
+				Send()
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if response.Gross.Amount != 15000 {
+				t.Errorf("Gross = %.2f, want 15000.00", response.Gross.Amount)
+			}
+
+			if response.Net.Amount != test.wantNet {
+				t.Errorf("Net = %.2f, want %.2f", response.Net.Amount, test.wantNet)
+			}
+		})
+	}
+}
+
+func TestParseSupplementalPaySpec(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SupplementalPaySpec
+		wantErr bool
+	}{
+		{value: "bonus=5000", want: SupplementalPaySpec{BonusEarningType, 5000, FlatSupplementalWithholdingMethod}},
+		{
+			value: "commission=1200.50:aggregate",
+			want:  SupplementalPaySpec{CommissionEarningType, 1200.5, AggregateSupplementalWithholdingMethod},
+		},
+		{value: "tips=80:flat", want: SupplementalPaySpec{TipsEarningType, 80, FlatSupplementalWithholdingMethod}},
+		{value: "other=0", want: SupplementalPaySpec{OtherSupplementalEarningType, 0, FlatSupplementalWithholdingMethod}},
+		{value: "overtime=100", wantErr: true},
+		{value: "bonus", wantErr: true},
+		{value: "bonus=lots", wantErr: true},
+		{value: "bonus=100:annualized", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseSupplementalPaySpec(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseSupplementalPaySpec() error = %v, wantErr %t", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("ParseSupplementalPaySpec() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+)
+
+// TaxYear returns the year of the request's pay date, which determines the tax tables used for the calculation.
+func (request *Request) TaxYear() (int, error) {
+	payDate, err := ParsePayDate(request.PayDate)
+	if err != nil {
+		return 0, err
+	}
+
+	return payDate.Year(), nil
+}
+
+// CacheKey returns a key that identifies the calculation of the request, for caching its response. It is the tax year
+// followed by a hash of every field of the request except for the pay date, so two requests have the same key if and
+// only if they are calculated the same way. The order of jurisdictions and statutory policy inputs does not affect the
+// key since it does not affect the calculation.
+func (request *Request) CacheKey() (string, error) {
+	taxYear, err := request.TaxYear()
+	if err != nil {
+		return "", err
+	}
+
+	canonical := *request
+	canonical.PayDate = ""
+	canonical.Jurisdictions.LivedInJurisdictions = sortedJurisdictions(request.Jurisdictions.LivedInJurisdictions)
+	canonical.Jurisdictions.WorkedInJurisdictions = sortedJurisdictions(request.Jurisdictions.WorkedInJurisdictions)
+	canonical.StatutoryPolicyInputs = slices.SortedStableFunc(slices.Values(request.StatutoryPolicyInputs),
+		func(a, b StatutoryPolicyInput) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+
+	canonicalJSON, err := json.Marshal(canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	hash := sha256.Sum256(canonicalJSON)
+
+	return fmt.Sprintf("%d-%s", taxYear, hex.EncodeToString(hash[:])), nil
+}
+
+// sortedJurisdictions returns a copy of the jurisdictions sorted by ID.
+func sortedJurisdictions(jurisdictions []*jurisdiction.Jurisdiction) []*jurisdiction.Jurisdiction {
+	return slices.SortedStableFunc(slices.Values(jurisdictions), func(a, b *jurisdiction.Jurisdiction) int {
+		return cmp.Compare(a.JurisdictionID, b.JurisdictionID)
+	})
+}
@@ -0,0 +1,98 @@
+package request
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// mustCacheKey builds the request from the builder and returns its cache key, failing the test on any error.
+func mustCacheKey(t *testing.T, builder *Builder, payDate string) string {
+	t.Helper()
+
+	request, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	request.PayDate = payDate
+
+	key, err := request.CacheKey()
+	if err != nil {
+		t.Fatalf("CacheKey() error = %v", err)
+	}
+
+	return key
+}
+
+func TestCacheKey(t *testing.T) {
+	ctx := context.Background()
+
+	_, registry := newTestServer(t)
+
+	newBuilder := func() *Builder {
+		return NewBuilder().WithRegistry(registry)
+	}
+
+	base := func() *Builder {
+		return newBuilder().WithSalary(100000, AnnualSalaryFrequency).WithJurisdictionsByCode(ctx, "CA")
+	}
+
+	baseKey := mustCacheKey(t, base(), "2024-06-01")
+
+	if !strings.HasPrefix(baseKey, "2024-") {
+		t.Errorf("CacheKey() = %q, want it to start with the tax year", baseKey)
+	}
+
+	if key := mustCacheKey(t, base(), "2024-12-31"); key != baseKey {
+		t.Errorf("CacheKey() changed with the pay date in the same year: %q != %q", key, baseKey)
+	}
+
+	reordered := newBuilder().
+		WithDependentsAmount(2000).
+		WithMultipleJobs(true).
+		WithJurisdictionsByCode(ctx, "NY", "NYC").
+		WithSalary(100000, AnnualSalaryFrequency)
+	ordered := newBuilder().
+		WithSalary(100000, AnnualSalaryFrequency).
+		WithJurisdictionsByCode(ctx, "NYC", "NY").
+		WithMultipleJobs(true).
+		WithDependentsAmount(2000)
+
+	if reorderedKey, orderedKey := mustCacheKey(t, reordered, "2024-06-01"),
+		mustCacheKey(t, ordered, "2024-06-01"); reorderedKey != orderedKey {
+		t.Errorf("CacheKey() depends on the order of options: %q != %q", reorderedKey, orderedKey)
+	}
+
+	different := map[string]*Builder{
+		"next tax year": base(),
+		"salary":        newBuilder().WithSalary(100001, AnnualSalaryFrequency).WithJurisdictionsByCode(ctx, "CA"),
+		"state":         newBuilder().WithSalary(100000, AnnualSalaryFrequency).WithJurisdictionsByCode(ctx, "CO"),
+		"pay frequency": base().WithPayFrequency(WeeklyPayFrequencyCode),
+		"filing status": base().WithFilingStatus(MarriedFilingJointlyFilingStatus),
+		"deduction":     base().WithDeduction(Traditional401kDeductionType, FlatDeductionAmountType, 100),
+		"lived in only": newBuilder().WithSalary(100000, AnnualSalaryFrequency).WithLivedInJurisdictionsByCode(ctx, "CA"),
+		"hourly":        base().WithHourly(10, 30),
+		"overtime":      base().WithOvertime(5, 30),
+		"double time":   base().WithDoubleTime(5, 30),
+		"periodic":      newBuilder().WithSalary(100000, PeriodicSalaryFrequency).WithJurisdictionsByCode(ctx, "CA"),
+	}
+
+	for name, builder := range different {
+		payDate := "2024-06-01"
+		if name == "next tax year" {
+			payDate = "2025-01-01"
+		}
+
+		if key := mustCacheKey(t, builder, payDate); key == baseKey {
+			t.Errorf("CacheKey() with a different %s = %q, want it to differ", name, key)
+		}
+	}
+}
+
+func TestCacheKeyInvalidPayDate(t *testing.T) {
+	_, err := (&Request{PayDate: "June 1st"}).CacheKey()
+	if err == nil {
+		t.Error("CacheKey() error = nil, want an error for an invalid pay date")
+	}
+}
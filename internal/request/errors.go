@@ -0,0 +1,60 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ValidationError is returned when the builder is given invalid input, such as a negative salary.
+type ValidationError struct {
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return err.Message
+}
+
+// UpstreamError is returned when the ADP API responds with a status other than OK. Both client errors (4xx) and server
+// errors (5xx) are represented by this type, except for 429 Too Many Requests, which is a [RateLimitedError].
+type UpstreamError struct {
+	StatusCode int
+	Status     string
+}
+
+func (err *UpstreamError) Error() string {
+	return fmt.Sprintf("status was not OK sending request: %s", err.Status)
+}
+
+// ServerError returns whether the ADP API responded with a server error (5xx). Server errors are assumed to be
+// transient and are retried.
+func (err *UpstreamError) ServerError() bool {
+	return err.StatusCode >= http.StatusInternalServerError
+}
+
+// RateLimitedError is returned when the ADP API responds with 429 Too Many Requests. If the response included a
+// Retry-After header in seconds, it is parsed into RetryAfter. Otherwise, RetryAfter is zero.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (err *RateLimitedError) Error() string {
+	if err.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited by ADP API, retry after %s", err.RetryAfter)
+	}
+
+	return "rate limited by ADP API"
+}
+
+// DecodeError is returned when the response from the ADP API cannot be decoded.
+type DecodeError struct {
+	Err error
+}
+
+func (err *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode response: %s", err.Err)
+}
+
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
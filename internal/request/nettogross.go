@@ -0,0 +1,196 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+const (
+	// netToGrossTolerance is how close the net pay must be to the target for the search to stop early. It is half a
+	// cent so that the net pay rounds to the target.
+	netToGrossTolerance = 0.005
+	// maxNetToGrossExpansions is the maximum number of times the upper bound of the search is extended before giving
+	// up.
+	maxNetToGrossExpansions = 16
+	// maxNetToGrossIterations is the maximum number of times the bounds are narrowed before the search returns its best
+	// result.
+	maxNetToGrossIterations = 40
+)
+
+// GrossToNetFunc calculates the response for a gross pay per pay period. It is called repeatedly by [SolveNetToGross],
+// so implementations may want to cache responses or rate limit requests.
+type GrossToNetFunc func(ctx context.Context, gross float64) (*response.Response, error)
+
+// netToGrossPoint is a gross pay per pay period that has been calculated along with its response.
+type netToGrossPoint struct {
+	gross    float64
+	response *response.Response
+}
+
+// net returns the net pay of the point.
+func (point *netToGrossPoint) net() float64 {
+	return point.response.Net.Amount
+}
+
+// SolveNetToGross searches for the gross pay per pay period that results in the target net pay per pay period. It
+// assumes that net pay increases with gross pay and never exceeds it. The target net pay is calculated as if it were
+// the gross pay first, and the share of it that is kept is used to estimate an upper bound. The search then
+// interpolates between the bounds, falling back to bisecting them when interpolating stops making progress, until the
+// net pay is within half a cent of the target or the bounds are within a cent of each other. It returns the smallest
+// gross pay found whose net pay is at least the target along with its response.
+//
+// Since withholding is linear in gross pay between tax brackets, this usually takes three or four calculations. In the
+// worst case, such as when net pay is not linear in gross pay, it takes at most 57.
+func SolveNetToGross(ctx context.Context, targetNet float64, calculate GrossToNetFunc) (
+	float64, *response.Response, error,
+) {
+	if targetNet < 0 || math.IsNaN(targetNet) || math.IsInf(targetNet, 0) {
+		return 0, nil, &ValidationError{Message: "target net pay must be a non-negative number"}
+	}
+
+	// The gross pay is at least the net pay, so that is the lower bound. If there are no taxes or deductions, it is
+	// also the answer.
+	low, err := calculateNetToGrossPoint(ctx, targetNet, calculate)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if low.net() >= targetNet-netToGrossTolerance {
+		return low.gross, low.response, nil
+	}
+
+	low, high, err := expandNetToGrossBound(ctx, targetNet, low, calculate)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// bisect is set once interpolating moves the same bound twice in a row, which happens when the bounds are in
+	// different tax brackets, so that the bounds are always at least halved every other step.
+	var (
+		bisect    bool
+		movedHigh bool
+	)
+
+	for iteration := range maxNetToGrossIterations {
+		if high.gross-low.gross <= 0.01 || math.Abs(high.net()-targetNet) < netToGrossTolerance {
+			break
+		}
+
+		middle := (low.gross + high.gross) / 2
+		if !bisect {
+			middle = interpolateNetToGross(targetNet, low, high)
+		}
+
+		middle = math.Min(math.Max(roundUpToCent(middle), low.gross+0.01), high.gross-0.01)
+
+		point, err := calculateNetToGrossPoint(ctx, middle, calculate)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		reachesTarget := point.net() >= targetNet-netToGrossTolerance
+		bisect = !bisect && iteration > 0 && reachesTarget == movedHigh
+		movedHigh = reachesTarget
+
+		if reachesTarget {
+			high = point
+		} else {
+			low = point
+		}
+	}
+
+	return high.gross, high.response, nil
+}
+
+// expandNetToGrossBound extrapolates from the lower bound to estimate a gross pay whose net pay reaches the target,
+// moving the lower bound up to each estimate that falls short. The first estimate assumes the same share of gross pay
+// is kept as at the lower bound, and later ones extend the line through the last two lower bounds. If net pay did not
+// increase, the gross pay is doubled instead. It returns the new lower bound and the upper bound.
+func expandNetToGrossBound(ctx context.Context, targetNet float64, low *netToGrossPoint, calculate GrossToNetFunc) (
+	*netToGrossPoint, *netToGrossPoint, error,
+) {
+	previous := &netToGrossPoint{response: &response.Response{}}
+
+	for range maxNetToGrossExpansions {
+		high := math.Max(low.gross*2, 0.01)
+
+		if slope := (low.net() - previous.net()) / (low.gross - previous.gross); slope > 0 {
+			high = math.Max(roundUpToCent(low.gross+(targetNet-low.net())/slope), low.gross+0.01)
+		}
+
+		point, err := calculateNetToGrossPoint(ctx, high, calculate)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if point.net() >= targetNet-netToGrossTolerance {
+			return low, point, nil
+		}
+
+		previous, low = low, point
+	}
+
+	return nil, nil, fmt.Errorf("could not find a gross pay with a net pay of at least %.2f", targetNet)
+}
+
+// interpolateNetToGross returns the gross pay where the line between the bounds reaches the target net pay.
+func interpolateNetToGross(targetNet float64, low, high *netToGrossPoint) float64 {
+	if high.net() <= low.net() {
+		return (low.gross + high.gross) / 2
+	}
+
+	return low.gross + (targetNet-low.net())*(high.gross-low.gross)/(high.net()-low.net())
+}
+
+// calculateNetToGrossPoint calculates the response for the gross pay.
+func calculateNetToGrossPoint(ctx context.Context, gross float64, calculate GrossToNetFunc) (
+	*netToGrossPoint, error,
+) {
+	grossResponse, err := calculate(ctx, gross)
+	if err != nil {
+		return nil, err
+	}
+
+	glog.V(10).Infof("Net to gross search: gross %.2f has net %.2f", gross, grossResponse.Net.Amount)
+
+	return &netToGrossPoint{gross: gross, response: grossResponse}, nil
+}
+
+// roundUpToCent rounds the amount up to the nearest cent. Amounts within a rounding error of a cent are rounded to it.
+func roundUpToCent(amount float64) float64 {
+	return math.Ceil(math.Round(amount*1e6)/1e4) / 100
+}
+
+// SendNetToGross finds the gross pay per pay period that results in the target net pay per pay period and returns it
+// along with its response. It is like [Builder.Send] except the builder must not have any salary or hourly income,
+// since the gross pay is solved for as a periodic salary with [SolveNetToGross]. Every other option of the builder,
+// such as the jurisdictions and deductions, is used as is. Each step of the search sends a request to the ADP API,
+// which is usually three or four requests in total.
+func (builder *Builder) SendNetToGross(targetNet float64) (float64, *response.Response, error) {
+	return builder.SendNetToGrossContext(context.Background(), targetNet)
+}
+
+// SendNetToGrossContext is like [Builder.SendNetToGross] but uses the context for the requests to the ADP API.
+func (builder *Builder) SendNetToGrossContext(ctx context.Context, targetNet float64) (
+	float64, *response.Response, error,
+) {
+	if err := builder.validate(); err != nil {
+		return 0, nil, err
+	}
+
+	if len(builder.salaries) > 0 || len(builder.hourlies) > 0 {
+		return 0, nil, &ValidationError{Message: "net to gross calculations must not have a salary or hourly income"}
+	}
+
+	glog.V(10).Infof("Solving for gross pay with net pay of %.2f", targetNet)
+
+	return SolveNetToGross(ctx, targetNet, func(ctx context.Context, gross float64) (*response.Response, error) {
+		step := *builder
+
+		return step.WithSalary(gross, PeriodicSalaryFrequency).SendContext(ctx)
+	})
+}
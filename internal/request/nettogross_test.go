@@ -0,0 +1,123 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// flatTax returns a gross to net function that withholds the rate from the gross pay and counts its calls.
+func flatTax(rate float64, calls *int) GrossToNetFunc {
+	return func(_ context.Context, gross float64) (*response.Response, error) {
+		*calls++
+
+		net := math.Round(gross*(1-rate)*100) / 100
+
+		return &response.Response{
+			Gross: response.SummaryEntity{Amount: gross},
+			Net:   response.SummaryEntity{Amount: net},
+		}, nil
+	}
+}
+
+// bracketTax returns a gross to net function that withholds 10% of the gross pay up to 1000 and 30% of the rest, and
+// counts its calls.
+func bracketTax(calls *int) GrossToNetFunc {
+	return func(_ context.Context, gross float64) (*response.Response, error) {
+		*calls++
+
+		tax := math.Min(gross, 1000)*0.1 + math.Max(gross-1000, 0)*0.3
+		net := math.Round((gross-tax)*100) / 100
+
+		return &response.Response{
+			Gross: response.SummaryEntity{Amount: gross},
+			Net:   response.SummaryEntity{Amount: net},
+		}, nil
+	}
+}
+
+func TestSolveNetToGross(t *testing.T) {
+	tests := []struct {
+		name      string
+		targetNet float64
+		calculate func(calls *int) GrossToNetFunc
+		wantGross float64
+		maxCalls  int
+	}{
+		{"no taxes", 1000, func(calls *int) GrossToNetFunc { return flatTax(0, calls) }, 1000, 1},
+		{"flat tax", 800, func(calls *int) GrossToNetFunc { return flatTax(0.2, calls) }, 1000, 2},
+		{"uneven tax", 6000, func(calls *int) GrossToNetFunc { return flatTax(0.2765, calls) }, 8293.02, 3},
+		{"tax brackets", 2000, bracketTax, 2571.43, 4},
+		{"zero", 0, func(calls *int) GrossToNetFunc { return flatTax(0.3, calls) }, 0, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+
+			gross, resp, err := SolveNetToGross(context.Background(), test.targetNet, test.calculate(&calls))
+			if err != nil {
+				t.Fatalf("SolveNetToGross() error = %v", err)
+			}
+
+			if math.Abs(gross-test.wantGross) > 0.01 {
+				t.Errorf("SolveNetToGross() gross = %.2f, want %.2f", gross, test.wantGross)
+			}
+
+			if resp.Net.Amount < test.targetNet-netToGrossTolerance {
+				t.Errorf("SolveNetToGross() net = %.2f, want at least %.2f", resp.Net.Amount, test.targetNet)
+			}
+
+			if calls > test.maxCalls {
+				t.Errorf("SolveNetToGross() made %d calculations, want at most %d", calls, test.maxCalls)
+			}
+		})
+	}
+}
+
+func TestSolveNetToGrossInvalidTarget(t *testing.T) {
+	calls := 0
+
+	_, _, err := SolveNetToGross(context.Background(), -1, flatTax(0.2, &calls))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("SolveNetToGross() error = %v, want a ValidationError", err)
+	}
+
+	if calls != 0 {
+		t.Errorf("SolveNetToGross() made %d calculations, want 0", calls)
+	}
+}
+
+func TestSendNetToGross(t *testing.T) {
+	ctx := context.Background()
+
+	server, registry := newTestServer(t)
+
+	gross, resp, err := NewBuilder(server.APIURL()).WithRegistry(registry).
+		WithPayFrequency(MonthlyPayFrequencyCode).
+		WithJurisdictionsByCode(ctx, "CA").
+		SendNetToGross(7435)
+	if err != nil {
+		t.Fatalf("SendNetToGross() error = %v", err)
+	}
+
+	if math.Abs(gross-10000) > 0.01 || math.Abs(resp.Net.Amount-7435) > 0.01 {
+		t.Errorf("SendNetToGross() gross, net = %.2f, %.2f, want 10000.00, 7435.00", gross, resp.Net.Amount)
+	}
+
+	if calls := server.CalculationCount(); calls > 4 {
+		t.Errorf("SendNetToGross() sent %d calculations, want at most 4", calls)
+	}
+
+	_, _, err = NewBuilder(server.APIURL()).WithSalary(1000, AnnualSalaryFrequency).SendNetToGross(100)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("SendNetToGross() with a salary error = %v, want a ValidationError", err)
+	}
+}
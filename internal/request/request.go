@@ -4,6 +4,8 @@ package request
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
@@ -12,6 +14,10 @@ import (
 // APIURL is the URL of the ADP API. Requests are POSTed to this URL.
 const APIURL = "https://paycheck-calculator.adp.com/api/pcc/v2/calculations"
 
+// MinTaxYear is the earliest tax year that calculations can be made for. The 2020 W4 form is always used, and ADP only
+// keeps the rules for recent years, so earlier years are rejected rather than calculated incorrectly.
+const MinTaxYear = 2020
+
 // Request represents the request to the ADP API, encoded as JSON.
 type Request struct {
 	CalculationTypeCode   CalculationTypeCode    `json:"calculationTypeCode"`
@@ -21,10 +27,21 @@ type Request struct {
 	PayFrequencyCode      PayFrequencyCode       `json:"payFrequencyCode"`
 	BusinessPolicies      []BusinessPolicy       `json:"businessPolicies"`
 	AdditionalEarnings    AdditionalEarnings     `json:"additionalEarnings"`
-	Deductions            []struct{}             `json:"deductions"`
+	Deductions            []Deduction            `json:"deductions"`
+}
+
+// ParsePayDate parses a pay date in the YYYY-MM-DD format used by the ADP API. The date is in UTC.
+func ParsePayDate(value string) (time.Time, error) {
+	payDate, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("pay date must be in the YYYY-MM-DD format: %w", err)
+	}
+
+	return payDate, nil
 }
 
-// CalculationTypeCode represents the calculation type code in the ADP API. Should always be GrossToNetTypeCode.
+// CalculationTypeCode represents the calculation type code in the ADP API. Should always be GrossToNetTypeCode. Net to
+// gross calculations are done by searching over gross to net calculations with [SolveNetToGross].
 type CalculationTypeCode struct {
 	Code string `json:"code"`
 }
@@ -43,18 +60,116 @@ type StatutoryPolicyInput struct {
 	TemplateID string      `json:"templateID"`
 }
 
+// federalW4TemplateID is the template ID shared by all of the statutory policy inputs from the federal W4 form.
+const federalW4TemplateID = "e01a6863-4fc7-4c2a-ac8c-f8d896c6fba2"
+
 var (
 	// StatutoryPolicy2020W4 is the statutory policy input that tells the calculation to use the 2020 and later W4
 	// form. If not specified, the calculation will use the 2019 and earlier W4 form.
-	StatutoryPolicy2020W4 = StatutoryPolicyInput{
-		ID:         "w4Form2020Indicator",
-		Name:       "w4Form2020Indicator",
-		Value:      true,
-		Type:       "boolean",
-		TemplateID: "e01a6863-4fc7-4c2a-ac8c-f8d896c6fba2",
+	StatutoryPolicy2020W4 = newFederalW4PolicyInput("w4Form2020Indicator", "boolean", true)
+)
+
+// newFederalW4PolicyInput creates a statutory policy input for a field of the federal W4 form. The ID is also used as
+// the name of the input.
+func newFederalW4PolicyInput(id, inputType string, value interface{}) StatutoryPolicyInput {
+	return StatutoryPolicyInput{
+		ID:         id,
+		Name:       id,
+		Value:      value,
+		Type:       inputType,
+		TemplateID: federalW4TemplateID,
 	}
+}
+
+// newFilingStatusPolicyInput creates the statutory policy input for step 1(c) of the W4.
+func newFilingStatusPolicyInput(filingStatus FilingStatus) StatutoryPolicyInput {
+	return newFederalW4PolicyInput("filingStatus", "list", filingStatus.Code)
+}
+
+// newMultipleJobsPolicyInput creates the statutory policy input for the checkbox in step 2(c) of the W4.
+func newMultipleJobsPolicyInput(multipleJobs bool) StatutoryPolicyInput {
+	return newFederalW4PolicyInput("multipleJobsIndicator", "boolean", multipleJobs)
+}
+
+// newDependentsPolicyInput creates the statutory policy input for the total dependents credit in step 3 of the W4.
+func newDependentsPolicyInput(amount float64) StatutoryPolicyInput {
+	return newFederalW4PolicyInput("dependentsAmount", "amount", amount)
+}
+
+// newOtherIncomePolicyInput creates the statutory policy input for the other income in step 4(a) of the W4.
+func newOtherIncomePolicyInput(amount float64) StatutoryPolicyInput {
+	return newFederalW4PolicyInput("otherIncomeAmount", "amount", amount)
+}
+
+// newDeductionsPolicyInput creates the statutory policy input for the deductions in step 4(b) of the W4.
+func newDeductionsPolicyInput(amount float64) StatutoryPolicyInput {
+	return newFederalW4PolicyInput("deductionsAmount", "amount", amount)
+}
+
+// newExtraWithholdingPolicyInput creates the statutory policy input for the extra withholding per pay period in step
+// 4(c) of the W4.
+func newExtraWithholdingPolicyInput(amount float64) StatutoryPolicyInput {
+	return newFederalW4PolicyInput("additionalTaxAmountPerPayPeriod", "amount", amount)
+}
+
+// FilingStatus is the federal filing status from step 1(c) of the W4.
+type FilingStatus struct {
+	Code string
+}
+
+var (
+	// SingleFilingStatus is the filing status for single or married filing separately.
+	SingleFilingStatus = FilingStatus{Code: "SINGLE"}
+	// MarriedFilingJointlyFilingStatus is the filing status for married filing jointly or qualifying surviving
+	// spouse.
+	MarriedFilingJointlyFilingStatus = FilingStatus{Code: "MARRIED_FILING_JOINTLY"}
+	// HeadOfHouseholdFilingStatus is the filing status for head of household.
+	HeadOfHouseholdFilingStatus = FilingStatus{Code: "HEAD_OF_HOUSEHOLD"}
 )
 
+func (fs FilingStatus) String() string {
+	switch fs {
+	case SingleFilingStatus:
+		return "single"
+	case MarriedFilingJointlyFilingStatus:
+		return "married"
+	case HeadOfHouseholdFilingStatus:
+		return "head-of-household"
+	default:
+		glog.V(10).Infof("Invalid filing status being converted to string: %+v", fs)
+
+		return ""
+	}
+}
+
+func (fs FilingStatus) validate() error {
+	switch fs {
+	case SingleFilingStatus, MarriedFilingJointlyFilingStatus, HeadOfHouseholdFilingStatus:
+		return nil
+	default:
+		return fmt.Errorf("invalid filing status: %s", fs.Code)
+	}
+}
+
+// Set sets the filing status from a string. It is necessary to implement the [flag.Value] interface. It returns an
+// error if the value is not recognized.
+func (fs *FilingStatus) Set(value string) error {
+	switch value {
+	case "single":
+		*fs = SingleFilingStatus
+	case "married", "married-filing-jointly":
+		*fs = MarriedFilingJointlyFilingStatus
+	case "head-of-household":
+		*fs = HeadOfHouseholdFilingStatus
+	default:
+		glog.V(10).Infof("Invalid filing status being set: %s", value)
+
+		return fmt.Errorf("invalid filing status: %s", value)
+	}
+
+	return nil
+}
+
 // Jurisdictions represents the jurisdictions that the calculation should be done for. This separates lived in and
 // worked in jurisdictions.
 type Jurisdictions struct {
@@ -69,26 +184,52 @@ type PayFrequencyCode struct {
 }
 
 var (
-	// MonthlyPayFrequencyCode is the pay frequency code for monthly payments.
-	MonthlyPayFrequencyCode = PayFrequencyCode{Code: "MONTHLY"}
-	// SemiMonthlyPayFrequencyCode is the pay frequency code for semi-monthly payments.
-	SemiMonthlyPayFrequencyCode = PayFrequencyCode{Code: "SEMI_MONTHLY"}
-	// BiWeeklyPayFrequencyCode is the pay frequency code for bi-weekly payments.
-	BiWeeklyPayFrequencyCode = PayFrequencyCode{Code: "BI_WEEKLY"}
+	// DailyPayFrequencyCode is the pay frequency code for daily payments.
+	DailyPayFrequencyCode = PayFrequencyCode{Code: "DAILY"}
 	// WeeklyPayFrequencyCode is the pay frequency code for weekly payments.
 	WeeklyPayFrequencyCode = PayFrequencyCode{Code: "WEEKLY"}
+	// BiWeeklyPayFrequencyCode is the pay frequency code for bi-weekly payments.
+	BiWeeklyPayFrequencyCode = PayFrequencyCode{Code: "BI_WEEKLY"}
+	// SemiMonthlyPayFrequencyCode is the pay frequency code for semi-monthly payments.
+	SemiMonthlyPayFrequencyCode = PayFrequencyCode{Code: "SEMI_MONTHLY"}
+	// MonthlyPayFrequencyCode is the pay frequency code for monthly payments.
+	MonthlyPayFrequencyCode = PayFrequencyCode{Code: "MONTHLY"}
+	// QuarterlyPayFrequencyCode is the pay frequency code for quarterly payments.
+	QuarterlyPayFrequencyCode = PayFrequencyCode{Code: "QUARTERLY"}
+	// SemiAnnualPayFrequencyCode is the pay frequency code for semi-annual payments.
+	SemiAnnualPayFrequencyCode = PayFrequencyCode{Code: "SEMI_ANNUAL"}
+	// AnnualPayFrequencyCode is the pay frequency code for annual payments.
+	AnnualPayFrequencyCode = PayFrequencyCode{Code: "ANNUAL"}
 )
 
+// payFrequencyAliases maps the other accepted spellings of pay frequencies to the name returned by
+// [PayFrequencyCode.String].
+var payFrequencyAliases = map[string]string{
+	"biweekly":    "bi-weekly",
+	"semimonthly": "semi-monthly",
+	"semiannual":  "semi-annual",
+	"annually":    "annual",
+	"yearly":      "annual",
+}
+
 func (pfc PayFrequencyCode) String() string {
 	switch pfc {
-	case MonthlyPayFrequencyCode:
-		return "monthly"
-	case SemiMonthlyPayFrequencyCode:
-		return "semi-monthly"
-	case BiWeeklyPayFrequencyCode:
-		return "bi-weekly"
+	case DailyPayFrequencyCode:
+		return "daily"
 	case WeeklyPayFrequencyCode:
 		return "weekly"
+	case BiWeeklyPayFrequencyCode:
+		return "bi-weekly"
+	case SemiMonthlyPayFrequencyCode:
+		return "semi-monthly"
+	case MonthlyPayFrequencyCode:
+		return "monthly"
+	case QuarterlyPayFrequencyCode:
+		return "quarterly"
+	case SemiAnnualPayFrequencyCode:
+		return "semi-annual"
+	case AnnualPayFrequencyCode:
+		return "annual"
 	default:
 		glog.V(10).Infof("Invalid pay frequency being converted to string: %+v", pfc)
 
@@ -96,22 +237,44 @@ func (pfc PayFrequencyCode) String() string {
 	}
 }
 
-// Set sets the pay frequency code from a string. It is necessary to implement the [flag.Value] interface. It does not
-// return an error and instead will default to monthly if the value is not recognized.
+// Set sets the pay frequency code from a string. It is necessary to implement the [flag.Value] interface. The value is
+// case insensitive and may be one of the names returned by [PayFrequencyCode.String] or an alias such as biweekly. It
+// returns an error if the value is not recognized.
 func (pfc *PayFrequencyCode) Set(value string) error {
-	switch value {
-	case "monthly":
-		*pfc = MonthlyPayFrequencyCode
-	case "semi-monthly":
-		*pfc = SemiMonthlyPayFrequencyCode
-	case "biweekly":
-		*pfc = BiWeeklyPayFrequencyCode
+	name := strings.ToLower(value)
+	if alias, ok := payFrequencyAliases[name]; ok {
+		name = alias
+	}
+
+	switch name {
+	case "daily":
+		*pfc = DailyPayFrequencyCode
 	case "weekly":
 		*pfc = WeeklyPayFrequencyCode
+	case "bi-weekly":
+		*pfc = BiWeeklyPayFrequencyCode
+	case "semi-monthly":
+		*pfc = SemiMonthlyPayFrequencyCode
+	case "monthly":
+		*pfc = MonthlyPayFrequencyCode
+	case "quarterly":
+		*pfc = QuarterlyPayFrequencyCode
+	case "semi-annual":
+		*pfc = SemiAnnualPayFrequencyCode
+	case "annual":
+		*pfc = AnnualPayFrequencyCode
 	default:
 		glog.V(10).Infof("Invalid pay frequency being set: %s", value)
 
-		*pfc = MonthlyPayFrequencyCode
+		return fmt.Errorf("invalid pay frequency: %s", value)
+	}
+
+	return nil
+}
+
+func (pfc PayFrequencyCode) validate() error {
+	if pfc.String() == "" {
+		return fmt.Errorf("invalid pay frequency: %s", pfc.Code)
 	}
 
 	return nil
@@ -151,6 +314,23 @@ func (f SalaryFrequency) String() string {
 	}
 }
 
+// Set sets the salary frequency from a string. It is necessary to implement the [flag.Value] interface. It returns an
+// error if the value is not recognized.
+func (f *SalaryFrequency) Set(value string) error {
+	switch value {
+	case "annual":
+		*f = AnnualSalaryFrequency
+	case "periodic":
+		*f = PeriodicSalaryFrequency
+	default:
+		glog.V(10).Infof("Invalid salary frequency being set: %s", value)
+
+		return fmt.Errorf("invalid salary frequency: %s", value)
+	}
+
+	return nil
+}
+
 func (f SalaryFrequency) validate() error {
 	switch f {
 	case AnnualSalaryFrequency, PeriodicSalaryFrequency:
@@ -199,13 +379,15 @@ type AdditionalEarnings struct {
 }
 
 // PayLine represents a line in the additional earnings such as the overtime pay. Unit is the number of hours worked and
-// amount is hourly rate prior to any client factor.
+// amount is hourly rate prior to any client factor. For supplemental pay such as a bonus, the unit is one and the
+// amount is the total, and the withholding method determines how federal income tax is withheld from it.
 type PayLine struct {
-	EarningType  EarningType   `json:"earningType"`
-	Unit         PayLineUnit   `json:"unit"`
-	Amount       PayLineAmount `json:"amount"`
-	Name         PayLineName   `json:"name"`
-	ClientFactor ClientFactor  `json:"clientFactor"`
+	EarningType       EarningType                    `json:"earningType"`
+	Unit              PayLineUnit                    `json:"unit"`
+	Amount            PayLineAmount                  `json:"amount"`
+	Name              PayLineName                    `json:"name"`
+	ClientFactor      ClientFactor                   `json:"clientFactor"`
+	WithholdingMethod *SupplementalWithholdingMethod `json:"supplementalWithholdingMethod,omitempty"`
 }
 
 func newOvertimePayLine(hours, rate float64) PayLine {
@@ -228,7 +410,7 @@ func newDoubleTimePayLine(hours, rate float64) PayLine {
 	}
 }
 
-// EarningType represents the type of earning such as overtime or double time.
+// EarningType represents the type of earning such as overtime, double time, or a bonus.
 type EarningType struct {
 	Value string `json:"value"`
 	Label string `json:"label"`
@@ -295,3 +477,198 @@ var (
 		Value: 2,
 	}
 )
+
+// Deduction is a deduction taken out of each paycheck, either before or after taxes. The value is either a flat amount
+// in dollars per pay period or a percent of the gross pay, depending on the amount type.
+type Deduction struct {
+	ID            string              `json:"id"`
+	DeductionType DeductionType       `json:"deductionType"`
+	AmountType    DeductionAmountType `json:"amountType"`
+	Value         float64             `json:"value"`
+}
+
+// newDeduction creates a new deduction. The index is used to create a unique ID for the deduction and should start at
+// 1.
+func newDeduction(deductionType DeductionType, amountType DeductionAmountType, value float64, index int) Deduction {
+	return Deduction{
+		ID:            fmt.Sprintf("deduction-%d", index),
+		DeductionType: deductionType,
+		AmountType:    amountType,
+		Value:         value,
+	}
+}
+
+// DeductionType represents the type of deduction such as a 401(k) contribution or a garnishment. Pre-tax deductions
+// reduce the taxable income while post-tax deductions do not.
+type DeductionType struct {
+	Value  string `json:"value"`
+	Label  string `json:"label"`
+	PreTax bool   `json:"preTax"`
+}
+
+var (
+	// Traditional401kDeductionType is the deduction type for pre-tax 401(k) contributions.
+	Traditional401kDeductionType = DeductionType{Value: "Traditional401k", Label: "401(k)", PreTax: true}
+	// Roth401kDeductionType is the deduction type for post-tax Roth 401(k) contributions.
+	Roth401kDeductionType = DeductionType{Value: "Roth401k", Label: "Roth 401(k)", PreTax: false}
+	// HSADeductionType is the deduction type for pre-tax health savings account contributions.
+	HSADeductionType = DeductionType{Value: "HealthSavingsAccount", Label: "HSA", PreTax: true}
+	// FSADeductionType is the deduction type for pre-tax flexible spending account contributions.
+	FSADeductionType = DeductionType{Value: "FlexibleSpendingAccount", Label: "FSA", PreTax: true}
+	// MedicalDeductionType is the deduction type for pre-tax medical insurance premiums.
+	MedicalDeductionType = DeductionType{Value: "MedicalPremium", Label: "Medical", PreTax: true}
+	// DentalDeductionType is the deduction type for pre-tax dental insurance premiums.
+	DentalDeductionType = DeductionType{Value: "DentalPremium", Label: "Dental", PreTax: true}
+	// GarnishmentDeductionType is the deduction type for post-tax wage garnishments.
+	GarnishmentDeductionType = DeductionType{Value: "Garnishment", Label: "Garnishment", PreTax: false}
+)
+
+func (dt DeductionType) String() string {
+	switch dt {
+	case Traditional401kDeductionType:
+		return "401k"
+	case Roth401kDeductionType:
+		return "roth-401k"
+	case HSADeductionType:
+		return "hsa"
+	case FSADeductionType:
+		return "fsa"
+	case MedicalDeductionType:
+		return "medical"
+	case DentalDeductionType:
+		return "dental"
+	case GarnishmentDeductionType:
+		return "garnishment"
+	default:
+		glog.V(10).Infof("Invalid deduction type being converted to string: %+v", dt)
+
+		return ""
+	}
+}
+
+// Set sets the deduction type from a string. It is necessary to implement the [flag.Value] interface. It returns an
+// error if the value is not recognized.
+func (dt *DeductionType) Set(value string) error {
+	switch value {
+	case "401k":
+		*dt = Traditional401kDeductionType
+	case "roth-401k":
+		*dt = Roth401kDeductionType
+	case "hsa":
+		*dt = HSADeductionType
+	case "fsa":
+		*dt = FSADeductionType
+	case "medical":
+		*dt = MedicalDeductionType
+	case "dental":
+		*dt = DentalDeductionType
+	case "garnishment":
+		*dt = GarnishmentDeductionType
+	default:
+		glog.V(10).Infof("Invalid deduction type being set: %s", value)
+
+		return fmt.Errorf("invalid deduction type: %s", value)
+	}
+
+	return nil
+}
+
+func (dt DeductionType) validate() error {
+	if dt.String() == "" {
+		return fmt.Errorf("invalid deduction type: %s", dt.Value)
+	}
+
+	return nil
+}
+
+// DeductionAmountType determines whether the value of a deduction is a flat amount or a percent of the gross pay.
+type DeductionAmountType struct {
+	Code string `json:"code"`
+}
+
+var (
+	// FlatDeductionAmountType is the amount type for deductions of a flat amount in dollars per pay period.
+	FlatDeductionAmountType = DeductionAmountType{Code: "FLAT"}
+	// PercentDeductionAmountType is the amount type for deductions of a percent of the gross pay.
+	PercentDeductionAmountType = DeductionAmountType{Code: "PERCENT"}
+)
+
+func (at DeductionAmountType) validate() error {
+	switch at {
+	case FlatDeductionAmountType, PercentDeductionAmountType:
+		return nil
+	default:
+		return fmt.Errorf("invalid deduction amount type: %s", at.Code)
+	}
+}
+
+// DeductionSpec is a deduction specified as a string of the form `type=amount`, such as `hsa=100` for a flat amount or
+// `401k=6%` for a percent of the gross pay. The type is one of 401k, roth-401k, hsa, fsa, medical, dental, or
+// garnishment.
+type DeductionSpec struct {
+	DeductionType DeductionType
+	AmountType    DeductionAmountType
+	Amount        float64
+}
+
+// ParseDeductionSpec parses a deduction of the form `type=amount`. If the amount ends with a percent sign, it is a
+// percent of the gross pay. Otherwise, it is a flat amount in dollars per pay period.
+func ParseDeductionSpec(value string) (DeductionSpec, error) {
+	typeString, amountString, ok := strings.Cut(value, "=")
+	if !ok {
+		return DeductionSpec{}, fmt.Errorf("deduction must be of the form type=amount: %s", value)
+	}
+
+	spec := DeductionSpec{AmountType: FlatDeductionAmountType}
+
+	err := spec.DeductionType.Set(typeString)
+	if err != nil {
+		return DeductionSpec{}, err
+	}
+
+	if trimmed, isPercent := strings.CutSuffix(amountString, "%"); isPercent {
+		spec.AmountType = PercentDeductionAmountType
+		amountString = trimmed
+	}
+
+	spec.Amount, err = strconv.ParseFloat(amountString, 64)
+	if err != nil {
+		return DeductionSpec{}, fmt.Errorf("deduction amount is not a valid float: %w", err)
+	}
+
+	return spec, nil
+}
+
+func (spec DeductionSpec) String() string {
+	if spec.AmountType == PercentDeductionAmountType {
+		return fmt.Sprintf("%s=%s%%", spec.DeductionType, strconv.FormatFloat(spec.Amount, 'f', -1, 64))
+	}
+
+	return fmt.Sprintf("%s=%s", spec.DeductionType, strconv.FormatFloat(spec.Amount, 'f', -1, 64))
+}
+
+// DeductionSpecs is a list of deductions that implements the [flag.Value] interface. Each call to [DeductionSpecs.Set]
+// appends a deduction, so the flag may be repeated.
+type DeductionSpecs []DeductionSpec
+
+func (specs DeductionSpecs) String() string {
+	specStrings := make([]string, len(specs))
+
+	for i, spec := range specs {
+		specStrings[i] = spec.String()
+	}
+
+	return strings.Join(specStrings, ",")
+}
+
+// Set parses the deduction and appends it to the list. It is necessary to implement the [flag.Value] interface.
+func (specs *DeductionSpecs) Set(value string) error {
+	spec, err := ParseDeductionSpec(value)
+	if err != nil {
+		return err
+	}
+
+	*specs = append(*specs, spec)
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package request
+
+import "testing"
+
+func TestPayFrequencyCodeSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PayFrequencyCode
+		wantErr bool
+	}{
+		{value: "daily", want: DailyPayFrequencyCode},
+		{value: "weekly", want: WeeklyPayFrequencyCode},
+		{value: "bi-weekly", want: BiWeeklyPayFrequencyCode},
+		{value: "biweekly", want: BiWeeklyPayFrequencyCode},
+		{value: "semi-monthly", want: SemiMonthlyPayFrequencyCode},
+		{value: "semimonthly", want: SemiMonthlyPayFrequencyCode},
+		{value: "Monthly", want: MonthlyPayFrequencyCode},
+		{value: "quarterly", want: QuarterlyPayFrequencyCode},
+		{value: "semi-annual", want: SemiAnnualPayFrequencyCode},
+		{value: "semiannual", want: SemiAnnualPayFrequencyCode},
+		{value: "annual", want: AnnualPayFrequencyCode},
+		{value: "yearly", want: AnnualPayFrequencyCode},
+		{value: "fortnightly", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			var got PayFrequencyCode
+
+			err := got.Set(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %t", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("Set() = %+v, want %+v", got, test.want)
+			}
+
+			if !test.wantErr && got.String() == "" {
+				t.Errorf("String() of %+v is empty", got)
+			}
+		})
+	}
+}
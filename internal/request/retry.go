@@ -0,0 +1,106 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy determines how failed requests to the ADP API are retried. Only transient failures are retried: network
+// timeouts and dropped connections, server errors (5xx), and rate limiting. The delay before each retry is chosen at
+// random up to an exponentially increasing bound, starting at BaseDelay and capped at MaxDelay.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first. Values less than two disable retries.
+	MaxAttempts int
+	// BaseDelay is the upper bound of the delay before the first retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the upper bound of the delay before any retry.
+	MaxDelay time.Duration
+}
+
+var (
+	// DefaultRetryPolicy is the retry policy used when a builder does not specify one.
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+	// NoRetryPolicy is a retry policy that never retries.
+	NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+)
+
+// delay returns how long to wait before retrying after the given attempt, which starts at 1. If the error is a
+// [RateLimitedError] with a Retry-After duration, that duration is used if it is longer than the jittered backoff.
+func (policy RetryPolicy) delay(attempt int, err error) time.Duration {
+	bound := policy.BaseDelay << (attempt - 1)
+	if bound > policy.MaxDelay || bound <= 0 {
+		bound = policy.MaxDelay
+	}
+
+	var delay time.Duration
+	if bound > 0 {
+		delay = rand.N(bound)
+	}
+
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > delay {
+		delay = rateLimitedErr.RetryAfter
+	}
+
+	return delay
+}
+
+// isRetryable returns whether the error from sending a request is transient and the request should be retried. Of the
+// network errors, only timeouts, temporary DNS failures, and connections that were refused or reset are retried, since
+// others such as a host that does not exist or a bad certificate will fail the same way again. Errors caused by the
+// context being done are never retried.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var (
+		upstreamErr    *UpstreamError
+		rateLimitedErr *RateLimitedError
+		dnsErr         *net.DNSError
+		netErr         net.Error
+	)
+
+	switch {
+	case errors.As(err, &upstreamErr):
+		return upstreamErr.ServerError()
+	case errors.As(err, &rateLimitedErr):
+		return true
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	case errors.As(err, &netErr):
+		return netErr.Timeout() || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header in seconds. HTTP dates and invalid values are treated as if
+// the header was not present and return zero.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// sleepContext waits for the duration or until the context is done, whichever is first. If the context is done first,
+// its error is returned.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
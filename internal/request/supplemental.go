@@ -0,0 +1,207 @@
+package request
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+var (
+	// BonusEarningType is the earning type for bonus pay.
+	BonusEarningType = EarningType{
+		Value: "Bonus",
+		Label: "BONUS",
+		Type:  "AMT",
+	}
+	// CommissionEarningType is the earning type for commission pay.
+	CommissionEarningType = EarningType{
+		Value: "Commission",
+		Label: "COMMISSION",
+		Type:  "AMT",
+	}
+	// TipsEarningType is the earning type for tips reported to the employer.
+	TipsEarningType = EarningType{
+		Value: "Tips",
+		Label: "TIPS",
+		Type:  "AMT",
+	}
+	// OtherSupplementalEarningType is the earning type for any other supplemental pay, such as severance or awards.
+	OtherSupplementalEarningType = EarningType{
+		Value: "OtherSupplementalPay",
+		Label: "OTHER_SUPPLEMENTAL",
+		Type:  "AMT",
+	}
+)
+
+// supplementalPayLineNames maps each supplemental earning type to the name of its pay line.
+var supplementalPayLineNames = map[EarningType]PayLineName{
+	BonusEarningType:             {Value: "Bonus"},
+	CommissionEarningType:        {Value: "Commission"},
+	TipsEarningType:              {Value: "Tips"},
+	OtherSupplementalEarningType: {Value: "Other supplemental pay"},
+}
+
+// SupplementalWithholdingMethod determines how federal income tax is withheld from supplemental pay. With the flat
+// method, it is withheld at the flat supplemental rate separately from the regular pay. With the aggregate method, it
+// is added to the regular pay and withheld as if the total was a regular paycheck.
+type SupplementalWithholdingMethod struct {
+	Code string `json:"code"`
+}
+
+var (
+	// FlatSupplementalWithholdingMethod withholds federal income tax from supplemental pay at the flat rate.
+	FlatSupplementalWithholdingMethod = SupplementalWithholdingMethod{Code: "FLAT"}
+	// AggregateSupplementalWithholdingMethod withholds federal income tax from supplemental pay as regular pay.
+	AggregateSupplementalWithholdingMethod = SupplementalWithholdingMethod{Code: "AGGREGATE"}
+)
+
+func (method SupplementalWithholdingMethod) String() string {
+	switch method {
+	case FlatSupplementalWithholdingMethod:
+		return "flat"
+	case AggregateSupplementalWithholdingMethod:
+		return "aggregate"
+	default:
+		glog.V(10).Infof("Invalid supplemental withholding method being converted to string: %+v", method)
+
+		return ""
+	}
+}
+
+// Set sets the supplemental withholding method from a string. It is necessary to implement the [flag.Value]
+// interface. It returns an error if the value is not recognized.
+func (method *SupplementalWithholdingMethod) Set(value string) error {
+	switch value {
+	case "flat":
+		*method = FlatSupplementalWithholdingMethod
+	case "aggregate":
+		*method = AggregateSupplementalWithholdingMethod
+	default:
+		glog.V(10).Infof("Invalid supplemental withholding method being set: %s", value)
+
+		return fmt.Errorf("invalid supplemental withholding method: %s", value)
+	}
+
+	return nil
+}
+
+func (method SupplementalWithholdingMethod) validate() error {
+	if method.String() == "" {
+		return fmt.Errorf("invalid supplemental withholding method: %s", method.Code)
+	}
+
+	return nil
+}
+
+// newSupplementalPayLine creates a pay line for a flat amount of supplemental pay with the withholding method.
+func newSupplementalPayLine(
+	earningType EarningType, amount float64, method SupplementalWithholdingMethod,
+) PayLine {
+	return PayLine{
+		EarningType:       earningType,
+		Unit:              newPayLineUnit(1),
+		Amount:            PayLineAmount{Value: amount},
+		Name:              supplementalPayLineNames[earningType],
+		ClientFactor:      ClientFactor{Value: 1},
+		WithholdingMethod: &method,
+	}
+}
+
+// SupplementalPaySpec is supplemental pay specified as a string of the form `type=amount` or `type=amount:method`, such
+// as `bonus=5000` or `commission=1200:aggregate`. The type is one of bonus, commission, tips, or other, and the method
+// is either flat or aggregate. If the method is not given, it is flat.
+type SupplementalPaySpec struct {
+	EarningType EarningType
+	Amount      float64
+	Method      SupplementalWithholdingMethod
+}
+
+// ParseSupplementalPaySpec parses supplemental pay of the form `type=amount` or `type=amount:method`. The amount is in
+// dollars for the pay period.
+func ParseSupplementalPaySpec(value string) (SupplementalPaySpec, error) {
+	typeString, amountString, ok := strings.Cut(value, "=")
+	if !ok {
+		return SupplementalPaySpec{}, fmt.Errorf("supplemental pay must be of the form type=amount: %s", value)
+	}
+
+	spec := SupplementalPaySpec{Method: FlatSupplementalWithholdingMethod}
+
+	switch typeString {
+	case "bonus":
+		spec.EarningType = BonusEarningType
+	case "commission":
+		spec.EarningType = CommissionEarningType
+	case "tips":
+		spec.EarningType = TipsEarningType
+	case "other":
+		spec.EarningType = OtherSupplementalEarningType
+	default:
+		return SupplementalPaySpec{}, fmt.Errorf("invalid supplemental pay type: %s", typeString)
+	}
+
+	amountString, methodString, hasMethod := strings.Cut(amountString, ":")
+	if hasMethod {
+		err := spec.Method.Set(methodString)
+		if err != nil {
+			return SupplementalPaySpec{}, err
+		}
+	}
+
+	var err error
+
+	spec.Amount, err = strconv.ParseFloat(amountString, 64)
+	if err != nil {
+		return SupplementalPaySpec{}, fmt.Errorf("supplemental pay amount is not a valid float: %w", err)
+	}
+
+	return spec, nil
+}
+
+func (spec SupplementalPaySpec) String() string {
+	return fmt.Sprintf("%s=%s:%s", supplementalPayTypeName(spec.EarningType),
+		strconv.FormatFloat(spec.Amount, 'f', -1, 64), spec.Method)
+}
+
+// supplementalPayTypeName returns the name of the supplemental earning type used in a [SupplementalPaySpec].
+func supplementalPayTypeName(earningType EarningType) string {
+	switch earningType {
+	case BonusEarningType:
+		return "bonus"
+	case CommissionEarningType:
+		return "commission"
+	case TipsEarningType:
+		return "tips"
+	case OtherSupplementalEarningType:
+		return "other"
+	default:
+		return ""
+	}
+}
+
+// SupplementalPaySpecs is a list of supplemental pay that implements the [flag.Value] interface. Each call to
+// [SupplementalPaySpecs.Set] appends supplemental pay, so the flag may be repeated.
+type SupplementalPaySpecs []SupplementalPaySpec
+
+func (specs SupplementalPaySpecs) String() string {
+	specStrings := make([]string, len(specs))
+
+	for i, spec := range specs {
+		specStrings[i] = spec.String()
+	}
+
+	return strings.Join(specStrings, ",")
+}
+
+// Set parses the supplemental pay and appends it to the list. It is necessary to implement the [flag.Value] interface.
+func (specs *SupplementalPaySpecs) Set(value string) error {
+	spec, err := ParseSupplementalPaySpec(value)
+	if err != nil {
+		return err
+	}
+
+	*specs = append(*specs, spec)
+
+	return nil
+}
@@ -1,4 +1,5 @@
-// Package response implements the types for the response from the ADP API. It currently holds no logic.
+// Package response implements the types for the response from the ADP API, along with detecting fields in responses
+// that the types do not know about.
 package response
 
 import "github.com/tslnc04/tax-calculator/internal/jurisdiction"
@@ -57,8 +58,16 @@ type TaxEntity struct {
 	ParentJurisdiction jurisdiction.Jurisdiction `json:"parentJurisdiction,omitempty"`
 }
 
-// Deductions contains all of the deductions for the response. The format of the entities has not been determined yet.
+// Deductions contains all of the deductions for the response, both pre-tax and post-tax.
 type Deductions struct {
-	Entities      []struct{}    `json:"entities"`
-	SummaryEntity SummaryEntity `json:"summaryEntity"`
+	Entities      []DeductionEntity `json:"entities"`
+	SummaryEntity SummaryEntity     `json:"summaryEntity"`
+}
+
+// DeductionEntity is a single deduction taken out of the gross income, such as a 401(k) contribution.
+type DeductionEntity struct {
+	Amount       float64 `json:"amount"`
+	CurrencyCode string  `json:"currencyCode"`
+	Label        string  `json:"label"`
+	PreTax       bool    `json:"preTax"`
 }
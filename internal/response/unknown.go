@@ -0,0 +1,112 @@
+package response
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// UnknownFields decodes the JSON response from the ADP API and returns the path of every field in it that [Response]
+// does not have, sorted and without duplicates. Paths join the JSON names of the fields with dots, and elements of
+// arrays are written as []. For example, a new field in a federal tax would be "taxes.federal.entities[].rate". Since
+// decoding a [Response] silently ignores these fields, they are a sign that the format of the response has changed.
+func UnknownFields(data []byte) ([]string, error) {
+	var decoded any
+
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+
+	collectUnknownFields(decoded, reflect.TypeFor[Response](), "", &unknown)
+
+	slices.Sort(unknown)
+
+	return slices.Compact(unknown), nil
+}
+
+// collectUnknownFields appends the path of every field in the decoded JSON value that the type does not have to
+// unknown. Values that do not have the shape of the type are skipped, since decoding them fails anyway.
+func collectUnknownFields(value any, valueType reflect.Type, path string, unknown *[]string) {
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	switch decoded := value.(type) {
+	case []any:
+		if valueType.Kind() != reflect.Slice && valueType.Kind() != reflect.Array {
+			return
+		}
+
+		for _, element := range decoded {
+			collectUnknownFields(element, valueType.Elem(), path+"[]", unknown)
+		}
+	case map[string]any:
+		if valueType.Kind() != reflect.Struct {
+			return
+		}
+
+		fields := jsonFields(valueType)
+
+		for name, fieldValue := range decoded {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+
+			fieldType, ok := lookupJSONField(fields, name)
+			if !ok {
+				*unknown = append(*unknown, fieldPath)
+
+				continue
+			}
+
+			collectUnknownFields(fieldValue, fieldType, fieldPath, unknown)
+		}
+	}
+}
+
+// jsonFields returns the types of the exported fields of the struct type by the names they have in JSON. Fields that
+// are ignored in JSON are left out.
+func jsonFields(structType reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, structType.NumField())
+
+	for i := range structType.NumField() {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+// lookupJSONField returns the type of the field with the JSON name. Like decoding, it prefers an exact match but falls
+// back to one that differs only in case.
+func lookupJSONField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if fieldType, ok := fields[name]; ok {
+		return fieldType, true
+	}
+
+	for fieldName, fieldType := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return fieldType, true
+		}
+	}
+
+	return nil, false
+}
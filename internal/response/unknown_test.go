@@ -0,0 +1,52 @@
+package response
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "known fields",
+			data: `{"net":{"amount":4000,"currencyCode":"USD","label":"Net"},"taxes":{"federal":{"entities":[` +
+				`{"amount":500,"jurisdiction":{"jurisdictionID":"id","jurisdictionCode":{"code":"US"}}}]}}}`,
+		},
+		{
+			name: "field names differing in case",
+			data: `{"Net":{"Amount":4000}}`,
+		},
+		{
+			name: "unknown fields",
+			data: `{"net":{"amount":4000,"rounding":0.01},"employer":{},"taxes":{"federal":{"entities":[` +
+				`{"amount":500,"rate":0.1},{"amount":100,"rate":0.02,"jurisdiction":{"jurisdictionType":"x"}}]}}}`,
+			want: []string{
+				"employer", "net.rounding", "taxes.federal.entities[].jurisdiction.jurisdictionType",
+				"taxes.federal.entities[].rate",
+			},
+		},
+		{
+			name:    "invalid JSON",
+			data:    `{"net":`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnknownFields([]byte(test.data))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("UnknownFields() error = %v, wantErr %t", err, test.wantErr)
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("UnknownFields() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
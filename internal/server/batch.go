@@ -0,0 +1,285 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+const (
+	// MaxBatchSize is the maximum number of scenarios in a single batch request. Since every scenario that is not cached
+	// waits for the rate limit, it is kept small enough that a full batch finishes in about a minute at the default
+	// rate limit of one request per second.
+	MaxBatchSize = 60
+	// maxBatchBodyBytes is the maximum size of the body of a batch request.
+	maxBatchBodyBytes = 10 << 20
+)
+
+// batchResult is the result of a single scenario in a batch request. Either the response or the error is set, and the
+// status is the HTTP status code the scenario would have had as its own request.
+type batchResult struct {
+	Status   int                `json:"status"`
+	Net      *float64           `json:"net,omitempty"`
+	Gross    *float64           `json:"gross,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Response *response.Response `json:"response,omitempty"`
+}
+
+// batchResponse is the JSON response for a batch request. The results are in the same order as the scenarios.
+type batchResponse struct {
+	Results []*batchResult `json:"results"`
+}
+
+// ServeBatch handles a request for calculating the net income of many scenarios at once. It only accepts POST requests
+// with a body that is either a JSON array of objects or, if the `Content-Type` is `text/csv`, a CSV file with a header
+// row. Each scenario takes the same parameters as the query string of [RequestHandler.ServeHTTP], with the object keys
+// or CSV columns as the parameter names. Parameters that can be repeated may be arrays in JSON or repeated columns in
+// CSV. Identical scenarios are only calculated once, and a scenario that fails does not fail the rest of the batch. By
+// default, it will return a CSV response with the status, net income, gross income, and error of each scenario. If
+// JSON is requested, the full response from the ADP API is included for each scenario instead.
+//
+// Scenarios are calculated one at a time and share the rate limit with every other request, so nothing is written
+// until the whole batch is done. In the worst case, a batch of [MaxBatchSize] uncached scenarios takes [MaxBatchSize]
+// times the rate limit, or a minute at the default of one request per second, plus another wait for each retry of a
+// failed request. Clients should set their timeouts accordingly.
+func (handler *RequestHandler) ServeBatch(resp http.ResponseWriter, req *http.Request) {
+	logRequest(req, "batch")
+
+	if req.Method != http.MethodPost {
+		resp.Header().Set("Allow", http.MethodPost)
+		http.Error(resp, "batch requests must use POST", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	format, err := negotiateFormat(req)
+	if err != nil {
+		glog.V(10).Infof("Failed to negotiate format: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to negotiate format: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	scenarios, err := parseBatch(resp, req)
+	if err != nil {
+		glog.V(10).Infof("Failed to parse batch: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to parse batch: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	glog.V(10).Infof("Calculating batch of %d scenarios", len(scenarios))
+
+	results := handler.calculateBatch(req.Context(), scenarios)
+
+	if format == jsonFormat {
+		writeJSON(resp, http.StatusOK, batchResponse{Results: results})
+
+		return
+	}
+
+	writeBatchCSV(resp, results)
+}
+
+// calculateBatch calculates the result of each scenario in order. The request for each scenario is built once, and
+// scenarios with the same cache key share a result so that identical scenarios are only calculated once.
+func (handler *RequestHandler) calculateBatch(ctx context.Context, scenarios []url.Values) []*batchResult {
+	results := make([]*batchResult, len(scenarios))
+	resultsByKey := map[string]*batchResult{}
+
+	for i, scenario := range scenarios {
+		params, err := parseRequestParams(scenario)
+		if err != nil {
+			results[i] = &batchResult{Status: http.StatusBadRequest, Error: err.Error()}
+
+			continue
+		}
+
+		builder := params.buildRequest(ctx, handler.apiURL, handler.registry)
+
+		cacheKey, taxYear, err := requestCacheKey(builder)
+		if err != nil {
+			results[i] = &batchResult{Status: statusForError(err), Error: err.Error()}
+
+			continue
+		}
+
+		if result, ok := resultsByKey[cacheKey]; ok {
+			results[i] = result
+
+			continue
+		}
+
+		results[i] = newBatchResult(handler.retrieveOrSend(ctx, builder, cacheKey, taxYear))
+		resultsByKey[cacheKey] = results[i]
+	}
+
+	return results
+}
+
+// newBatchResult creates the result of a scenario from the response for it or the error calculating it.
+func newBatchResult(response *response.Response, err error) *batchResult {
+	if err != nil {
+		return &batchResult{Status: statusForError(err), Error: err.Error()}
+	}
+
+	return &batchResult{
+		Status:   http.StatusOK,
+		Net:      &response.Net.Amount,
+		Gross:    &response.Gross.Amount,
+		Response: response,
+	}
+}
+
+// parseBatch reads the scenarios from the body of a batch request. The body is parsed as CSV if the `Content-Type` is
+// `text/csv` and as JSON otherwise.
+func parseBatch(resp http.ResponseWriter, req *http.Request) ([]url.Values, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, maxBatchBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var scenarios []url.Values
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == csvContentType {
+		scenarios, err = parseBatchCSV(body)
+	} else {
+		scenarios, err = parseBatchJSON(body)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scenarios) > MaxBatchSize {
+		return nil, fmt.Errorf("batch has %d scenarios, which is more than the maximum of %d", len(scenarios),
+			MaxBatchSize)
+	}
+
+	return scenarios, nil
+}
+
+// parseBatchJSON parses a JSON array of scenarios. Each scenario is an object of parameter names to values, which may
+// be strings, numbers, booleans, or arrays of those for parameters that can be repeated.
+func parseBatchJSON(body []byte) ([]url.Values, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var objects []map[string]any
+
+	err := decoder.Decode(&objects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	scenarios := make([]url.Values, len(objects))
+
+	for i, object := range objects {
+		scenarios[i] = url.Values{}
+
+		for name, value := range object {
+			values, ok := value.([]any)
+			if !ok {
+				values = []any{value}
+			}
+
+			for _, value := range values {
+				text, err := jsonParamString(value)
+				if err != nil {
+					return nil, fmt.Errorf("scenario %d has an invalid value for %s: %w", i, name, err)
+				}
+
+				scenarios[i].Add(name, text)
+			}
+		}
+	}
+
+	return scenarios, nil
+}
+
+// jsonParamString converts a decoded JSON value into the string that would have been used in a query parameter.
+func jsonParamString(value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	default:
+		return "", errors.New("must be a string, number, or boolean")
+	}
+}
+
+// parseBatchCSV parses CSV scenarios. The first row is the header with the parameter names, and each following row is a
+// scenario. Empty cells are ignored, and a parameter may be given in more than one column to repeat it.
+func parseBatchCSV(body []byte) ([]url.Values, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("CSV must have a header row")
+	}
+
+	header := records[0]
+	scenarios := make([]url.Values, len(records)-1)
+
+	for i, record := range records[1:] {
+		scenarios[i] = url.Values{}
+
+		for column, value := range record {
+			if value = strings.TrimSpace(value); value != "" {
+				scenarios[i].Add(strings.TrimSpace(header[column]), value)
+			}
+		}
+	}
+
+	return scenarios, nil
+}
+
+// writeBatchCSV writes the results of a batch request as CSV with a header row. Errors writing the response are logged
+// since the status code has already been sent.
+func writeBatchCSV(resp http.ResponseWriter, results []*batchResult) {
+	resp.Header().Set("Content-Type", csvContentType)
+	resp.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(resp)
+	_ = writer.Write([]string{"status", "net", "gross", "error"})
+
+	for _, result := range results {
+		record := []string{strconv.Itoa(result.Status), "", "", result.Error}
+
+		if result.Response != nil {
+			record[1] = strconv.FormatFloat(*result.Net, 'f', 2, 64)
+			record[2] = strconv.FormatFloat(*result.Gross, 'f', 2, 64)
+		}
+
+		_ = writer.Write(record)
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		glog.V(10).Infof("Failed to write CSV response: %s", err)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveBatch sends a POST request with the body and content type to the batch endpoint and returns the recorded
+// response.
+func serveBatch(handler http.Handler, query, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, APIBasePath+"/batch"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestServeBatchJSON(t *testing.T) {
+	server, handler := newTestHandler(t)
+
+	body := `[
+		{"salary": 120000, "state": "CA", "pay-frequency": "monthly"},
+		{"salary": "lots"},
+		{"salary": 120000, "state": "CA", "pay-frequency": "monthly"},
+		{"salary": 60000, "state": "ZZ"},
+		{"salary": 60000, "deduction": ["401k=5%", "medical=100"]}
+	]`
+
+	recorder := serveBatch(handler, "?format=json", jsonContentType, body)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	decoded := &batchResponse{}
+
+	err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+	if err != nil {
+		t.Fatalf("failed to decode JSON response: %s", err)
+	}
+
+	wantStatuses := []int{http.StatusOK, http.StatusBadRequest, http.StatusOK, http.StatusNotFound, http.StatusOK}
+	if len(decoded.Results) != len(wantStatuses) {
+		t.Fatalf("got %d results, want %d", len(decoded.Results), len(wantStatuses))
+	}
+
+	for i, result := range decoded.Results {
+		if result.Status != wantStatuses[i] {
+			t.Errorf("result %d status = %d, want %d: %s", i, result.Status, wantStatuses[i], result.Error)
+		}
+
+		if (result.Error == "") != (result.Status == http.StatusOK) {
+			t.Errorf("result %d error = %q with status %d", i, result.Error, result.Status)
+		}
+	}
+
+	if net := decoded.Results[0].Net; net == nil || *net != 7435 {
+		t.Errorf("result 0 net = %v, want 7435.00", net)
+	}
+
+	if deductions := decoded.Results[4].Response.Deductions.Entities; len(deductions) != 2 {
+		t.Errorf("result 4 deductions = %+v, want two", deductions)
+	}
+
+	if calls := server.CalculationCount(); calls != 2 {
+		t.Errorf("server received %d calls, want 2", calls)
+	}
+}
+
+func TestServeBatchCSV(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	body := "salary,state,pay-frequency,deduction,deduction\n" +
+		"120000,CA,monthly,,\n" +
+		"-5,,,,\n" +
+		"120000,TX,monthly,401k=10%,medical=50\n"
+
+	recorder := serveBatch(handler, "", csvContentType, body)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4: %q", len(lines), lines)
+	}
+
+	if lines[0] != "status,net,gross,error" {
+		t.Errorf("header = %q, want %q", lines[0], "status,net,gross,error")
+	}
+
+	if lines[1] != "200,7435.00,10000.00," {
+		t.Errorf("first result = %q, want %q", lines[1], "200,7435.00,10000.00,")
+	}
+
+	if !strings.HasPrefix(lines[2], "400,,,") {
+		t.Errorf("second result = %q, want a 400 error", lines[2])
+	}
+
+	if !strings.HasPrefix(lines[3], "200,") {
+		t.Errorf("third result = %q, want a 200", lines[3])
+	}
+}
+
+func TestServeBatchErrors(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"invalid JSON", jsonContentType, `{"salary": 1000}`},
+		{"invalid JSON value", jsonContentType, `[{"salary": {"amount": 1000}}]`},
+		{"ragged CSV", csvContentType, "salary,state\n1000\n"},
+		{"empty CSV", csvContentType, ""},
+		{"too many scenarios", jsonContentType, "[" + strings.Repeat(`{"salary": 1000},`, MaxBatchSize) + "{}]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serveBatch(handler, "", test.contentType, test.body)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+			}
+		})
+	}
+
+	recorder := serve(handler, APIBasePath+"/batch")
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
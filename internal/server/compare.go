@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/compare"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// MaxCompareStates is the maximum number of states that may be listed in a single compare request. Since every state
+// that is not cached waits for the rate limit, longer lists are rejected. Comparing every state with
+// [compare.AllStates] is still allowed, since it is bounded by the number of states.
+const MaxCompareStates = 10
+
+// compareResponse is the JSON response for a comparison. It contains a result for each state, sorted from the highest
+// net income to the lowest.
+type compareResponse struct {
+	Results []compare.Result `json:"results"`
+}
+
+// ServeCompare handles a request for comparing the net income of a salary in several states. It expects at most
+// [MaxCompareStates] states to be specified as a comma separated list of codes with the `states` query parameter, or
+// `all` for every state, and accepts every other parameter of [RequestHandler.ServeHTTP] except for state, live-state,
+// and work-state. Each state is calculated through the cache and rate limit like a single request, so a comparison of
+// n uncached states takes n times the rate limit. By default, it will return a CSV response with the net income, state
+// taxes, total taxes, and difference from the best state for each state. If JSON is requested, the same results are
+// returned as JSON.
+func (handler *RequestHandler) ServeCompare(resp http.ResponseWriter, req *http.Request) {
+	logRequest(req, "compare")
+
+	format, err := negotiateFormat(req)
+	if err != nil {
+		glog.V(10).Infof("Failed to negotiate format: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to negotiate format: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	states, params, err := parseCompareParams(req.Context(), handler.registry, req.URL.Query())
+	if err != nil {
+		glog.V(10).Infof("Failed to parse request params: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to parse request params: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	results, err := compare.Run(req.Context(), handler.registry, states,
+		func(ctx context.Context, state string) (*response.Response, error) {
+			step := *params
+			step.state = state
+
+			return handler.retrieveOrRequest(ctx, &step)
+		})
+	if err != nil {
+		glog.V(10).Infof("Failed to compare states: %s", err)
+
+		writeError(resp, "failed to compare states", err)
+
+		return
+	}
+
+	glog.V(10).Infof("Responding with %d results to compare request", len(results))
+
+	if format == jsonFormat {
+		writeJSON(resp, http.StatusOK, compareResponse{Results: results})
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", csvContentType)
+	resp.WriteHeader(http.StatusOK)
+
+	err = compare.WriteCSV(resp, results)
+	if err != nil {
+		glog.V(10).Infof("Failed to write CSV response: %s", err)
+	}
+}
+
+// parseCompareParams parses the states to compare and every other request parameter for a comparison. The states are
+// loaded from the registry if they include [compare.AllStates], and otherwise there may be at most [MaxCompareStates]
+// of them. State, live-state, and work-state may not be used.
+func parseCompareParams(
+	ctx context.Context, registry *jurisdiction.Registry, query url.Values,
+) ([]string, *requestParams, error) {
+	params, err := parseRequestParams(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if params.state != "" || params.liveState != "" || params.workState != "" {
+		return nil, nil, errors.New("state, live-state, and work-state cannot be used with compare")
+	}
+
+	codes := strings.Split(strings.Join(query["states"], ","), ",")
+
+	states, err := compare.StateCodes(ctx, registry, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(states) > MaxCompareStates && !compare.IncludesAllStates(codes) {
+		return nil, nil, fmt.Errorf("states must have at most %d states, got %d", MaxCompareStates, len(states))
+	}
+
+	return states, params, nil
+}
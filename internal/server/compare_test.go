@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServeCompare(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, APIBasePath+"/compare?salary=120000&states=CA,TX,NY&pay-frequency=monthly")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4: %q", len(lines), lines)
+	}
+
+	for i, state := range []string{"TX", "NY", "CA"} {
+		if !strings.HasPrefix(lines[i+1], state+",") {
+			t.Errorf("row %d = %q, want state %s", i+1, lines[i+1], state)
+		}
+	}
+
+	if !strings.HasSuffix(lines[3], ",800.00,2565.00,-800.00") {
+		t.Errorf("last row = %q, want CA 800.00 behind TX", lines[3])
+	}
+
+	recorder = serve(handler, APIBasePath+"/compare?salary=120000&states=all&format=json")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	decoded := &compareResponse{}
+
+	err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+	if err != nil {
+		t.Fatalf("failed to decode JSON response: %s", err)
+	}
+
+	if len(decoded.Results) != 51 || decoded.Results[0].Difference != 0 {
+		t.Errorf("got %d results, want 51 starting with the best state", len(decoded.Results))
+	}
+}
+
+func TestServeCompareErrors(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"missing states", APIBasePath + "/compare?salary=1000", http.StatusBadRequest},
+		{"missing salary", APIBasePath + "/compare?states=CA", http.StatusBadRequest},
+		{"with state", APIBasePath + "/compare?salary=1000&states=CA&state=TX", http.StatusBadRequest},
+		{"unknown state", APIBasePath + "/compare?salary=1000&states=CA,ZZ", http.StatusNotFound},
+		{"too many states", APIBasePath + "/compare?salary=1000&states=AK,AL,AR,AZ,CA,CO,CT,DC,DE,FL,GA",
+			http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(handler, test.target)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/request"
+)
+
+// statusForError maps an error from retrieving a response to the HTTP status code that should be returned. Invalid
+// input is the client's fault, while failures of the ADP API are reported as a bad gateway or gateway timeout.
+func statusForError(err error) int {
+	var (
+		validationErr  *request.ValidationError
+		notFoundErr    *jurisdiction.NotFoundError
+		rateLimitedErr *request.RateLimitedError
+		upstreamErr    *request.UpstreamError
+		decodeErr      *request.DecodeError
+		netErr         net.Error
+	)
+
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound
+	case errors.As(err, &rateLimitedErr):
+		return http.StatusTooManyRequests
+	case errors.As(err, &upstreamErr), errors.As(err, &decodeErr):
+		return http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout
+	case errors.As(err, &netErr):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes the error with the status code from [statusForError]. If the ADP API rate limited the request and
+// said when to retry, the Retry-After header is passed along.
+func writeError(resp http.ResponseWriter, message string, err error) {
+	status := statusForError(err)
+
+	var rateLimitedErr *request.RateLimitedError
+	if errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > 0 {
+		resp.Header().Set("Retry-After", strconv.Itoa(int(rateLimitedErr.RetryAfter.Seconds())))
+	}
+
+	http.Error(resp, message+": "+err.Error(), status)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// outputFormat is the format that a response is written in.
+type outputFormat int
+
+const (
+	// csvFormat writes the response as CSV. This is the default format.
+	csvFormat outputFormat = iota
+	// jsonFormat writes the response as JSON.
+	jsonFormat
+)
+
+const (
+	csvContentType  = "text/csv"
+	jsonContentType = "application/json"
+)
+
+// negotiateFormat determines the output format of the response. The `format` query parameter takes precedence over the
+// `Accept` header. If neither is specified or the `Accept` header does not match a supported format, CSV is used. An
+// error is only returned if the `format` query parameter is not a supported format.
+func negotiateFormat(req *http.Request) (outputFormat, error) {
+	if format := req.URL.Query().Get("format"); format != "" {
+		switch strings.ToLower(format) {
+		case "csv":
+			return csvFormat, nil
+		case "json":
+			return jsonFormat, nil
+		default:
+			return csvFormat, fmt.Errorf("unsupported format: %s", format)
+		}
+	}
+
+	bestFormat := csvFormat
+	bestQuality := -1.0
+
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if quality <= bestQuality || quality <= 0 {
+			continue
+		}
+
+		switch mediaType {
+		case jsonContentType:
+			bestFormat, bestQuality = jsonFormat, quality
+		case csvContentType, "text/*", "*/*":
+			bestFormat, bestQuality = csvFormat, quality
+		}
+	}
+
+	return bestFormat, nil
+}
+
+// writeJSON writes the value as JSON with the given status code. Errors writing the response are logged since the
+// status code has already been sent.
+func writeJSON(resp http.ResponseWriter, status int, value any) {
+	resp.Header().Set("Content-Type", jsonContentType)
+	resp.WriteHeader(status)
+
+	err := json.NewEncoder(resp).Encode(value)
+	if err != nil {
+		glog.V(10).Infof("Failed to write JSON response: %s", err)
+	}
+}
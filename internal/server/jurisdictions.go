@@ -0,0 +1,57 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+// pccVersionHeader is the response header that holds the version of the ADP PCC that the jurisdictions were loaded
+// from, since it has no place in the CSV response.
+const pccVersionHeader = "X-PCC-Version"
+
+// ServeJurisdictions handles a request for listing every jurisdiction whose code may be used with the state, local,
+// live-state, and work-state parameters of [RequestHandler.ServeHTTP]. By default, it will return a CSV response with
+// the ID, code, name, level, and parent state of each jurisdiction, with the PCC version in the `X-PCC-Version` header.
+// If JSON is requested, the same jurisdictions are returned as JSON along with the PCC version.
+func (handler *RequestHandler) ServeJurisdictions(resp http.ResponseWriter, req *http.Request) {
+	logRequest(req, "jurisdictions")
+
+	format, err := negotiateFormat(req)
+	if err != nil {
+		glog.V(10).Infof("Failed to negotiate format: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to negotiate format: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	listing, err := handler.registry.List(req.Context())
+	if err != nil {
+		glog.V(10).Infof("Failed to list jurisdictions: %s", err)
+
+		writeError(resp, "failed to list jurisdictions", err)
+
+		return
+	}
+
+	glog.V(10).Infof("Responding with %d jurisdictions from PCC version %s", len(listing.Jurisdictions),
+		listing.PCCVersion)
+
+	resp.Header().Set(pccVersionHeader, listing.PCCVersion)
+
+	if format == jsonFormat {
+		writeJSON(resp, http.StatusOK, listing)
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", csvContentType)
+	resp.WriteHeader(http.StatusOK)
+
+	err = listing.WriteCSV(resp)
+	if err != nil {
+		glog.V(10).Infof("Failed to write CSV response: %s", err)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/request"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// netToGrossResponse is the JSON response for a net to gross calculation. It contains the gross salary per pay period
+// that results in the target net income, along with the full response from the ADP API for that salary.
+type netToGrossResponse struct {
+	Gross    float64            `json:"gross"`
+	Response *response.Response `json:"response"`
+}
+
+// netToGrossExcludedParams are the query parameters that set the salary or hourly pay, which cannot be used with a net
+// to gross request since the gross salary per pay period is what is solved for.
+var netToGrossExcludedParams = []string{
+	"salary", "salary-frequency", "hourly-rate", "hours", "overtime-hours", "doubletime-hours",
+}
+
+// ServeNetToGross handles a request for calculating the gross salary per pay period needed for a target net income. It
+// expects the target net income per pay period to be specified as a float with the `net` query parameter, and accepts
+// every other parameter of [RequestHandler.ServeHTTP] except for the [netToGrossExcludedParams]. By default, it will
+// return a CSV response with the gross salary. If JSON is requested, the gross salary is returned along with the full
+// response from the ADP API. The gross salary is solved for with [request.SolveNetToGross], which usually takes three
+// or four rate limited requests to the ADP API.
+func (handler *RequestHandler) ServeNetToGross(resp http.ResponseWriter, req *http.Request) {
+	logRequest(req, "net to gross")
+
+	format, err := negotiateFormat(req)
+	if err != nil {
+		glog.V(10).Infof("Failed to negotiate format: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to negotiate format: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	targetNet, params, err := parseNetToGrossParams(req.URL.Query())
+	if err != nil {
+		glog.V(10).Infof("Failed to parse request params: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to parse request params: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	gross, response, err := request.SolveNetToGross(req.Context(), targetNet,
+		func(ctx context.Context, gross float64) (*response.Response, error) {
+			step := *params
+			step.salary = gross
+			step.salaryFrequency = request.PeriodicSalaryFrequency
+
+			return handler.retrieveOrRequest(ctx, &step)
+		})
+	if err != nil {
+		glog.V(10).Infof("Failed to solve net to gross: %s", err)
+
+		writeError(resp, "failed to solve net to gross", err)
+
+		return
+	}
+
+	glog.V(10).Infof("Responding with gross %.2f to net to gross request for %.2f", gross, targetNet)
+
+	if format == jsonFormat {
+		writeJSON(resp, http.StatusOK, netToGrossResponse{Gross: gross, Response: response})
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", csvContentType)
+	resp.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(resp, "%.2f\n", gross)
+}
+
+// parseNetToGrossParams parses the target net income per pay period and every other request parameter for a net to
+// gross request. Any of the [netToGrossExcludedParams] is an error.
+func parseNetToGrossParams(query url.Values) (float64, *requestParams, error) {
+	for _, name := range netToGrossExcludedParams {
+		if query.Has(name) {
+			return 0, nil, fmt.Errorf("%s cannot be used with net to gross", name)
+		}
+	}
+
+	net := query.Get("net")
+	if net == "" {
+		return 0, nil, fmt.Errorf("net must be specified")
+	}
+
+	targetNet, err := strconv.ParseFloat(net, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("net is not a valid float: %w", err)
+	}
+
+	params, err := parseOptionParams(query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return targetNet, params, nil
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestServeNetToGross(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, APIBasePath+"/net-to-gross?net=7435&state=CA&pay-frequency=monthly")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	if body := recorder.Body.String(); body != "10000.00\n" {
+		t.Errorf("body = %q, want %q", body, "10000.00\n")
+	}
+
+	recorder = serve(handler, APIBasePath+"/net-to-gross?net=7435&state=CA&pay-frequency=monthly&format=json")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	decoded := &netToGrossResponse{}
+
+	err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+	if err != nil {
+		t.Fatalf("failed to decode JSON response: %s", err)
+	}
+
+	if decoded.Gross != 10000 || decoded.Response == nil || decoded.Response.Net.Amount != 7435 {
+		t.Errorf("decoded response = %+v, want gross 10000.00 with net 7435.00", decoded)
+	}
+}
+
+func TestServeNetToGrossErrors(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"missing net", APIBasePath + "/net-to-gross?state=CA"},
+		{"invalid net", APIBasePath + "/net-to-gross?net=lots"},
+		{"negative net", APIBasePath + "/net-to-gross?net=-100"},
+		{"with salary", APIBasePath + "/net-to-gross?net=1000&salary=2000"},
+		{"with salary frequency", APIBasePath + "/net-to-gross?net=1000&salary-frequency=annual"},
+		{"with hourly rate", APIBasePath + "/net-to-gross?net=1000&hourly-rate=25"},
+		{"with hours", APIBasePath + "/net-to-gross?net=1000&hourly-rate=25&hours=80"},
+		{"with overtime hours", APIBasePath + "/net-to-gross?net=1000&overtime-hours=5"},
+		{"with doubletime hours", APIBasePath + "/net-to-gross?net=1000&doubletime-hours=5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(handler, test.target)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/request"
+)
+
+type requestParams struct {
+	salary           float64
+	salaryFrequency  request.SalaryFrequency
+	hourlyRate       float64
+	hours            float64
+	overtimeHours    float64
+	doubletimeHours  float64
+	payFrequency     request.PayFrequencyCode
+	payDate          time.Time
+	state            string
+	locals           []string
+	liveState        string
+	workState        string
+	filingStatus     request.FilingStatus
+	multipleJobs     bool
+	dependents       float64
+	otherIncome      float64
+	w4Deductions     float64
+	extraWithholding float64
+	deductions       request.DeductionSpecs
+	supplementalPay  request.SupplementalPaySpecs
+}
+
+// parseRequestParams parses the request parameters from the query and returns a new requestParams struct. The salary
+// may only be omitted if an hourly rate is given instead.
+func parseRequestParams(query url.Values) (*requestParams, error) {
+	params, err := parseOptionParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	salary := query.Get("salary")
+	if salary == "" {
+		if params.hourlyRate == 0 {
+			return nil, fmt.Errorf("salary or hourly-rate must be specified")
+		}
+
+		return params, nil
+	}
+
+	params.salary, err = strconv.ParseFloat(salary, 64)
+	if err != nil {
+		return nil, fmt.Errorf("salary is not a valid float: %w", err)
+	}
+
+	return params, nil
+}
+
+// parseOptionParams parses every request parameter except for the salary, which is left as zero with an annual
+// frequency. It is used directly by endpoints that determine the salary themselves.
+func parseOptionParams(query url.Values) (*requestParams, error) {
+	params := &requestParams{
+		salaryFrequency: request.AnnualSalaryFrequency,
+		state:           query.Get("state"),
+		liveState:       query.Get("live-state"),
+		workState:       query.Get("work-state"),
+	}
+
+	err := params.setValueParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	err = params.setFloatParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if payDate := query.Get("pay-date"); payDate != "" {
+		params.payDate, err = request.ParsePayDate(payDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if multipleJobs := query.Get("multiple-jobs"); multipleJobs != "" {
+		params.multipleJobs, err = strconv.ParseBool(multipleJobs)
+		if err != nil {
+			return nil, fmt.Errorf("multiple-jobs is not a valid bool: %w", err)
+		}
+	}
+
+	if params.hourlyRate == 0 && (params.hours != 0 || params.overtimeHours != 0 || params.doubletimeHours != 0) {
+		return nil, fmt.Errorf("hourly-rate must be specified with hours, overtime-hours, or doubletime-hours")
+	}
+
+	for _, locals := range query["local"] {
+		params.locals = append(params.locals, strings.Split(locals, ",")...)
+	}
+
+	return params, nil
+}
+
+// valueParam is a query parameter that is parsed by a flag value. A repeated parameter is set with every value, and
+// any other parameter only with its first value if that is not empty.
+type valueParam struct {
+	name     string
+	value    flag.Value
+	repeated bool
+}
+
+// setValueParams sets the fields of the params that are flag values from the query.
+func (params *requestParams) setValueParams(query url.Values) error {
+	valueParams := []valueParam{
+		{name: "pay-frequency", value: &params.payFrequency},
+		{name: "salary-frequency", value: &params.salaryFrequency},
+		{name: "filing-status", value: &params.filingStatus},
+		{name: "deduction", value: &params.deductions, repeated: true},
+		{name: "supplemental", value: &params.supplementalPay, repeated: true},
+	}
+
+	for _, valueParam := range valueParams {
+		values := query[valueParam.name]
+		if !valueParam.repeated {
+			values = nil
+
+			if value := query.Get(valueParam.name); value != "" {
+				values = []string{value}
+			}
+		}
+
+		for _, value := range values {
+			err := valueParam.value.Set(value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setFloatParams sets the fields of the params that are floats from the query. Parameters that are not specified are
+// left as zero.
+func (params *requestParams) setFloatParams(query url.Values) error {
+	floatParams := []struct {
+		name  string
+		value *float64
+	}{
+		{"hourly-rate", &params.hourlyRate},
+		{"hours", &params.hours},
+		{"overtime-hours", &params.overtimeHours},
+		{"doubletime-hours", &params.doubletimeHours},
+		{"dependents", &params.dependents},
+		{"other-income", &params.otherIncome},
+		{"w4-deductions", &params.w4Deductions},
+		{"extra-withholding", &params.extraWithholding},
+	}
+
+	for _, floatParam := range floatParams {
+		var err error
+
+		*floatParam.value, err = parseFloatParam(query, floatParam.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseFloatParam parses the query parameter with the given name as a float. If the parameter is not specified, zero
+// is returned.
+func parseFloatParam(query url.Values, name string) (float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	valueFloat, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid float: %w", name, err)
+	}
+
+	return valueFloat, nil
+}
+
+// buildRequest creates a new request builder for the API URL with the parameters from the request. Jurisdictions are
+// looked up in the registry, which is loaded with the context if needed. A salary of zero is left out when there is an
+// hourly rate, since the hourly pay is the only income then.
+func (params *requestParams) buildRequest(
+	ctx context.Context, apiURL string, registry *jurisdiction.Registry,
+) *request.Builder {
+	builder := request.NewBuilder(apiURL).WithRegistry(registry)
+
+	params.addPay(builder)
+	params.addJurisdictions(ctx, builder)
+	params.addW4(builder)
+
+	builder.WithDeductionSpecs(params.deductions...)
+	builder.WithSupplementalPaySpecs(params.supplementalPay...)
+
+	return builder
+}
+
+// addPay adds the pay frequency, salary, hourly pay, and pay date parameters to the builder.
+func (params *requestParams) addPay(builder *request.Builder) {
+	if params.payFrequency != (request.PayFrequencyCode{}) {
+		builder.WithPayFrequency(params.payFrequency)
+	}
+
+	if params.salary != 0 || params.hourlyRate == 0 {
+		builder.WithSalary(params.salary, params.salaryFrequency)
+	}
+
+	if params.hourlyRate != 0 {
+		glog.V(10).Infof("Adding hourly rate to request: %.2f for %.2f hours", params.hourlyRate, params.hours)
+
+		builder.WithHourly(params.hours, params.hourlyRate)
+	}
+
+	if params.overtimeHours != 0 {
+		builder.WithOvertime(params.overtimeHours, params.hourlyRate)
+	}
+
+	if params.doubletimeHours != 0 {
+		builder.WithDoubleTime(params.doubletimeHours, params.hourlyRate)
+	}
+
+	if !params.payDate.IsZero() {
+		builder.WithPayDate(params.payDate)
+	}
+}
+
+// addJurisdictions adds the state, local, lived in, and worked in jurisdictions to the builder, loading them with the
+// context if needed.
+func (params *requestParams) addJurisdictions(ctx context.Context, builder *request.Builder) {
+	if params.state != "" {
+		glog.V(10).Infof("Adding state to request: %s", params.state)
+
+		builder.WithJurisdictionsByCode(ctx, params.state)
+	}
+
+	if len(params.locals) > 0 {
+		glog.V(10).Infof("Adding local jurisdictions to request: %v", params.locals)
+
+		builder.WithJurisdictionsByCode(ctx, params.locals...)
+	}
+
+	if params.liveState != "" {
+		glog.V(10).Infof("Adding lived in state to request: %s", params.liveState)
+
+		builder.WithLivedInJurisdictionsByCode(ctx, params.liveState)
+	}
+
+	if params.workState != "" {
+		glog.V(10).Infof("Adding worked in state to request: %s", params.workState)
+
+		builder.WithWorkedInJurisdictionsByCode(ctx, params.workState)
+	}
+}
+
+// addW4 adds the W4 parameters to the builder. Parameters that are not set are left out so that ADP's defaults are
+// used for them.
+func (params *requestParams) addW4(builder *request.Builder) {
+	if params.filingStatus != (request.FilingStatus{}) {
+		builder.WithFilingStatus(params.filingStatus)
+	}
+
+	if params.multipleJobs {
+		builder.WithMultipleJobs(params.multipleJobs)
+	}
+
+	if params.dependents != 0 {
+		builder.WithDependentsAmount(params.dependents)
+	}
+
+	if params.otherIncome != 0 {
+		builder.WithOtherIncome(params.otherIncome)
+	}
+
+	if params.w4Deductions != 0 {
+		builder.WithW4Deductions(params.w4Deductions)
+	}
+
+	if params.extraWithholding != 0 {
+		builder.WithExtraWithholding(params.extraWithholding)
+	}
+}
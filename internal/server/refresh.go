@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// RefreshJurisdictions checks whether ADP has published a new PCC version and, if so, reloads the jurisdictions in the
+// registry. Since cached responses were calculated with the jurisdictions from the old version, the response cache is
+// purged when the version changes.
+func (handler *RequestHandler) RefreshJurisdictions(ctx context.Context) error {
+	previousVersion := handler.registry.PCCVersion()
+
+	changed, err := handler.registry.Refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		glog.V(10).Infof("PCC version is still %s", previousVersion)
+
+		return nil
+	}
+
+	pccVersion := handler.registry.PCCVersion()
+
+	glog.Infof("PCC version changed from %q to %q, purging response cache", previousVersion, pccVersion)
+
+	handler.cache.Purge()
+
+	return nil
+}
+
+// RefreshPeriodically calls [RequestHandler.RefreshJurisdictions] every interval until the context is done. If the
+// jurisdictions have not been loaded from ADP yet, either because nothing has been loaded or because only the embedded
+// snapshot could be, they are refreshed once right away instead of waiting for the first interval. Failures are logged
+// and the loaded jurisdictions are kept until the next refresh succeeds.
+func (handler *RequestHandler) RefreshPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	glog.V(10).Infof("Refreshing jurisdictions every %s", interval)
+
+	if !handler.registry.Loaded() || handler.registry.Embedded() {
+		glog.V(10).Info("Jurisdictions have not been loaded from ADP, refreshing now")
+
+		handler.refreshOrWarn(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			handler.refreshOrWarn(ctx)
+		}
+	}
+}
+
+// refreshOrWarn calls [RequestHandler.RefreshJurisdictions] and logs a warning if it fails.
+func (handler *RequestHandler) refreshOrWarn(ctx context.Context) {
+	err := handler.RefreshJurisdictions(ctx)
+	if err != nil {
+		glog.Warningf("Failed to refresh jurisdictions, keeping PCC version %q: %s", handler.registry.PCCVersion(), err)
+	}
+}
+
+// PCCVersion returns the version of the ADP PCC that the handler's jurisdictions were loaded from. It is empty until
+// they have been loaded.
+func (handler *RequestHandler) PCCVersion() string {
+	return handler.registry.PCCVersion()
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+)
+
+func TestRefreshJurisdictions(t *testing.T) {
+	server, options := newTestOptions(t)
+
+	handler, err := NewRequestHandler(options)
+	if err != nil {
+		t.Fatalf("NewRequestHandler() error = %v", err)
+	}
+
+	// calculate serves the same request each time so that it is only sent to the server if the cache was purged.
+	calculate := func() {
+		t.Helper()
+
+		recorder := serve(handler, APIBasePath+"/?salary=60000&state=CA")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+		}
+	}
+
+	calculate()
+
+	err = handler.RefreshJurisdictions(context.Background())
+	if err != nil {
+		t.Fatalf("RefreshJurisdictions() error = %v", err)
+	}
+
+	calculate()
+
+	if calls := server.CalculationCount(); calls != 1 {
+		t.Errorf("server received %d calls after refreshing the same version, want 1", calls)
+	}
+
+	server.FailNext(http.StatusInternalServerError)
+
+	err = handler.RefreshJurisdictions(context.Background())
+	if err == nil {
+		t.Fatal("RefreshJurisdictions() error = nil, want an error")
+	}
+
+	if version := handler.PCCVersion(); version != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion() after failed refresh = %q, want %q", version, fakeadp.PCCVersion)
+	}
+
+	server.SetPCCVersion("2025.1.0")
+
+	err = handler.RefreshJurisdictions(context.Background())
+	if err != nil {
+		t.Fatalf("RefreshJurisdictions() error = %v", err)
+	}
+
+	if version := handler.PCCVersion(); version != "2025.1.0" {
+		t.Errorf("PCCVersion() = %q, want 2025.1.0", version)
+	}
+
+	calculate()
+
+	if calls := server.CalculationCount(); calls != 2 {
+		t.Errorf("server received %d calls after the version changed, want 2", calls)
+	}
+}
+
+func TestRefreshPeriodically(t *testing.T) {
+	server, options := newTestOptions(t)
+
+	handler, err := NewRequestHandler(options)
+	if err != nil {
+		t.Fatalf("NewRequestHandler() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		handler.RefreshPeriodically(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	server.SetPCCVersion("2025.1.0")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for handler.PCCVersion() != "2025.1.0" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	if version := handler.PCCVersion(); version != "2025.1.0" {
+		t.Errorf("PCCVersion() = %q, want 2025.1.0", version)
+	}
+}
+
+func TestRefreshPeriodicallyReplacesEmbeddedSnapshotAtStartup(t *testing.T) {
+	server := fakeadp.NewServer()
+	t.Cleanup(server.Close)
+
+	registry := jurisdiction.NewRegistry(&jurisdiction.Loader{BaseURL: server.URL})
+
+	server.FailNext(http.StatusInternalServerError)
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if !registry.Embedded() {
+		t.Fatal("Embedded() after failing to reach ADP = false, want true")
+	}
+
+	handler, err := NewRequestHandler(Options{
+		CacheSize: 10, RateLimit: time.Millisecond, APIURL: server.APIURL(), Registry: registry,
+	})
+	if err != nil {
+		t.Fatalf("NewRequestHandler() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// The interval is long enough that only the refresh at startup can replace the embedded snapshot.
+	go func() {
+		handler.RefreshPeriodically(ctx, time.Hour)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for registry.Embedded() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	if registry.Embedded() {
+		t.Fatal("Embedded() after refreshing at startup = true, want false")
+	}
+
+	recorder := serve(handler, APIBasePath+"/?salary=60000&state=CA")
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+}
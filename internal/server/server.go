@@ -6,12 +6,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"net/url"
-	"strconv"
 	"time"
 
 	"github.com/golang/glog"
-	lruv2 "github.com/hashicorp/golang-lru/v2"
+	"github.com/tslnc04/tax-calculator/internal/cache"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
 	"github.com/tslnc04/tax-calculator/internal/request"
 	"github.com/tslnc04/tax-calculator/internal/response"
 	"golang.org/x/time/rate"
@@ -22,51 +21,132 @@ const (
 	APIBasePath = "/api/v1"
 )
 
+// Options configures the taxcalcd web server.
+type Options struct {
+	// Cache is the cache for responses from the ADP API. If nil, a cache is created from CacheDir or CacheSize.
+	Cache cache.Cache
+	// CacheDir is the directory of a [cache.Disk] that keeps responses across restarts. If empty, responses are kept in
+	// a [cache.Memory] instead.
+	CacheDir string
+	// CacheSize is the number of responses to keep in the response cache, whether it is in memory or on disk.
+	CacheSize int
+	// RateLimit is the minimum duration between requests to the ADP API.
+	RateLimit time.Duration
+	// APIURL is the URL of the ADP API that requests are sent to. If empty, [request.APIURL] is used.
+	APIURL string
+	// Registry is where jurisdictions are looked up by code. If nil, [jurisdiction.DefaultRegistry] is used.
+	Registry *jurisdiction.Registry
+}
+
 // NewRequestMux attaches all the routes for the taxcalcd web server to a ServeMux. It returns the ServeMux and an error
 // if one occurred.
-func NewRequestMux(cacheSize int, rateLimit time.Duration) (*http.ServeMux, error) {
-	requestHandler, err := NewRequestHandler(cacheSize, rateLimit)
+func NewRequestMux(options Options) (*http.ServeMux, error) {
+	requestHandler, err := NewRequestHandler(options)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewServeMux(requestHandler), nil
+}
+
+// NewServeMux attaches all the routes for the taxcalcd web server to a ServeMux that is served by the request handler.
+// It is useful when the handler is needed for more than serving requests, such as refreshing jurisdictions.
+func NewServeMux(requestHandler *RequestHandler) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.Handle(APIBasePath+"/", requestHandler)
+	mux.HandleFunc(APIBasePath+"/net-to-gross", requestHandler.ServeNetToGross)
+	mux.HandleFunc(APIBasePath+"/batch", requestHandler.ServeBatch)
+	mux.HandleFunc(APIBasePath+"/sweep", requestHandler.ServeSweep)
+	mux.HandleFunc(APIBasePath+"/compare", requestHandler.ServeCompare)
+	mux.HandleFunc(APIBasePath+"/jurisdictions", requestHandler.ServeJurisdictions)
 	mux.HandleFunc("/", HandleHealthCheck)
 
-	return mux, nil
+	return mux
 }
 
-type responseCache = *lruv2.Cache[string, *response.Response]
-
 // RequestHandler is a handler for the taxcalcd web server. It includes a cache for storing responses from the ADP API.
 // Its zero value is not valid and must be initialized with [NewRequestHandler].
 type RequestHandler struct {
-	cache   responseCache
-	limiter *rate.Limiter
+	cache    cache.Cache
+	limiter  *rate.Limiter
+	apiURL   string
+	registry *jurisdiction.Registry
 }
 
-// NewRequestHandler creates a new request handler with the given cache size and rate limit. Each cached response will
-// consume roughly 600 bytes. Requests to the ADP API are rate limited to one per the given rate limit.
-func NewRequestHandler(cacheSize int, rateLimit time.Duration) (*RequestHandler, error) {
-	cache, err := lruv2.New[string, *response.Response](cacheSize)
+// NewRequestHandler creates a new request handler with the given options. Requests to the ADP API are rate limited to
+// one per the rate limit.
+func NewRequestHandler(options Options) (*RequestHandler, error) {
+	responseCache, err := newCache(options)
 	if err != nil {
 		return nil, err
 	}
 
-	limiter := rate.NewLimiter(rate.Every(rateLimit), 1)
-	handler := &RequestHandler{cache: cache, limiter: limiter}
+	apiURL := options.APIURL
+	if apiURL == "" {
+		apiURL = request.APIURL
+	}
+
+	registry := options.Registry
+	if registry == nil {
+		registry = jurisdiction.DefaultRegistry
+	}
+
+	limiter := rate.NewLimiter(rate.Every(options.RateLimit), 1)
+	handler := &RequestHandler{cache: responseCache, limiter: limiter, apiURL: apiURL, registry: registry}
 
 	return handler, nil
 }
 
+// Limiter returns the rate limiter that every request from the handler to the ADP API waits on. Other requests to the
+// ADP API, such as the startup check, can wait on it as well so that they share the rate limit.
+func (handler *RequestHandler) Limiter() request.Limiter {
+	return handler.limiter
+}
+
+// Registry returns the registry that the handler looks up jurisdictions in.
+func (handler *RequestHandler) Registry() *jurisdiction.Registry {
+	return handler.registry
+}
+
+// newCache returns the cache from the options, or creates the one they describe.
+func newCache(options Options) (cache.Cache, error) {
+	switch {
+	case options.Cache != nil:
+		return options.Cache, nil
+	case options.CacheDir != "":
+		glog.V(10).Infof("Using disk cache in %s", options.CacheDir)
+
+		return cache.NewDisk(options.CacheDir, options.CacheSize)
+	default:
+		return cache.NewMemory(options.CacheSize)
+	}
+}
+
 // ServeHTTP handles a request for calculating the net income. It expects the salary to be specified in the query string
-// as a float and the pay frequency and state as strings. It will return a CSV response with the net income.
+// as a float and the pay frequency and state as strings. The salary is annual unless salary-frequency is periodic.
+// Hourly pay may be given with hourly-rate and hours instead of or in addition to the salary, and overtime-hours and
+// doubletime-hours are paid at 1.5 and 2 times the hourly rate. The live-state and work-state parameters may be used
+// instead of state when living and working in different states, and local may list the codes of local jurisdictions.
+// The W4 inputs filing-status, multiple-jobs, dependents, other-income, w4-deductions, and extra-withholding may
+// optionally be specified as well, along with any number of deduction parameters of the form type=amount and
+// supplemental parameters of the form type=amount[:method] for bonuses and other supplemental pay. The pay-date
+// parameter sets the date of the paycheck as YYYY-MM-DD, which determines the tax year. By default, it will return a
+// CSV response with the net income. If JSON is requested with the `format` query parameter or the `Accept` header, the
+// full response from the ADP API is returned instead.
 func (handler *RequestHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	logRequest(req, "API")
 
-	params, err := parseRequestParams(req.URL)
+	format, err := negotiateFormat(req)
+	if err != nil {
+		glog.V(10).Infof("Failed to negotiate format: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to negotiate format: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	params, err := parseRequestParams(req.URL.Query())
 	if err != nil {
 		glog.V(10).Infof("Failed to parse request params: %s", err)
 
@@ -75,75 +155,54 @@ func (handler *RequestHandler) ServeHTTP(resp http.ResponseWriter, req *http.Req
 		return
 	}
 
-	response, err := params.retrieveOrRequest(handler.cache, handler.limiter)
+	response, err := handler.retrieveOrRequest(req.Context(), params)
 	if err != nil {
 		glog.V(10).Infof("Failed to retrieve or request: %s", err)
 
-		http.Error(resp, fmt.Sprintf("failed to retrieve or request: %s", err), http.StatusInternalServerError)
+		writeError(resp, "failed to retrieve or request", err)
 
 		return
 	}
 
 	glog.V(10).Infof("Responding with %.2f to request with params %+v", response.Net.Amount, params)
 
-	resp.Header().Set("Content-Type", "text/csv")
-	resp.WriteHeader(http.StatusOK)
-
-	fmt.Fprintf(resp, "%.2f\n", response.Net.Amount)
-}
-
-type requestParams struct {
-	salary       float64
-	payFrequency request.PayFrequencyCode
-	state        string
-}
-
-// parseRequestParams parses the request parameters from the URL and returns a new requestParams struct.
-func parseRequestParams(url *url.URL) (*requestParams, error) {
-	salary := url.Query().Get("salary")
-	if salary == "" {
-		return nil, fmt.Errorf("salary must be specified")
-	}
+	if format == jsonFormat {
+		writeJSON(resp, http.StatusOK, response)
 
-	salaryFloat, err := strconv.ParseFloat(salary, 64)
-	if err != nil {
-		return nil, fmt.Errorf("salary is not a valid float: %w", err)
+		return
 	}
 
-	payFrequency := url.Query().Get("pay-frequency")
-	payFrequencyCode := request.PayFrequencyCode{}
-	_ = payFrequencyCode.Set(payFrequency)
-
-	state := url.Query().Get("state")
-
-	return &requestParams{salaryFloat, payFrequencyCode, state}, nil
-}
+	resp.Header().Set("Content-Type", csvContentType)
+	resp.WriteHeader(http.StatusOK)
 
-// getCacheKey returns a string representation of the parameters that can be used as a cache key.
-func (params *requestParams) getCacheKey() string {
-	return fmt.Sprintf("%.2f%s%s", params.salary, params.state, params.payFrequency)
+	fmt.Fprintf(resp, "%.2f\n", response.Net.Amount)
 }
 
-// buildRequest creates a new request builder with the parameters from the request.
-func (params *requestParams) buildRequest() *request.Builder {
-	builder := request.NewBuilder().
-		WithSalary(params.salary, request.AnnualSalaryFrequency).
-		WithPayFrequency(params.payFrequency)
-
-	if params.state != "" {
-		glog.V(10).Infof("Adding state to request: %s", params.state)
+// retrieveOrRequest attempts to retrieve a response from the cache or send a request to the ADP API. It will rate limit
+// every attempt to send the request to the ADP API, including retries. Waiting for the rate limit and the request are
+// abandoned once the context is done.
+func (handler *RequestHandler) retrieveOrRequest(ctx context.Context, params *requestParams) (
+	*response.Response, error,
+) {
+	builder := params.buildRequest(ctx, handler.apiURL, handler.registry)
+
+	cacheKey, taxYear, err := requestCacheKey(builder)
+	if err != nil {
+		glog.V(10).Infof("Failed to get cache key: %s", err)
 
-		builder.WithJurisdictionsByCode(params.state)
+		return nil, err
 	}
 
-	return builder
+	return handler.retrieveOrSend(ctx, builder, cacheKey, taxYear)
 }
 
-// retrieveOrRequest attempts to retrieve a response from the cache or send a request to the ADP API. It will rate limit
-// requests to the ADP API.
-func (params *requestParams) retrieveOrRequest(cache responseCache, limiter *rate.Limiter) (*response.Response, error) {
-	cacheKey := params.getCacheKey()
-	cachedResponse, ok := cache.Get(cacheKey)
+// retrieveOrSend retrieves the response for the cache key from the cache, or sends the builder's request to the ADP API
+// with the rate limit and caches the response for the tax year if it is not there. The cache key and tax year must be
+// the ones returned by [requestCacheKey] for the builder.
+func (handler *RequestHandler) retrieveOrSend(
+	ctx context.Context, builder *request.Builder, cacheKey string, taxYear int,
+) (*response.Response, error) {
+	cachedResponse, ok := handler.cache.Get(cacheKey)
 
 	if ok {
 		glog.V(10).Infof("Found entry in cache for key `%s`, using cached response", cacheKey)
@@ -151,27 +210,40 @@ func (params *requestParams) retrieveOrRequest(cache responseCache, limiter *rat
 		return cachedResponse, nil
 	}
 
-	glog.V(10).Infof("No entry in cache for key `%s`, waiting for rate limit", cacheKey)
+	glog.V(10).Infof("No entry in cache for key `%s`, sending request to ADP API", cacheKey)
 
-	err := limiter.Wait(context.Background())
+	response, err := builder.WithLimiter(handler.limiter).SendContext(ctx)
 	if err != nil {
-		glog.V(10).Infof("Failed to wait for rate limit: %s", err)
+		glog.V(10).Infof("Failed to send request to ADP API: %s", err)
 
-		return nil, fmt.Errorf("failed to wait for rate limit: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	glog.V(10).Info("Successfully waited for rate limit, sending request to ADP API")
+	handler.cache.Add(cacheKey, taxYear, response)
 
-	response, err := params.buildRequest().Send()
+	return response, nil
+}
+
+// requestCacheKey returns the key for the response to the builder's request in the cache along with the tax year of the
+// request, which determines when the response expires. Invalid requests, such as ones for unknown jurisdictions, return
+// the builder's error.
+func requestCacheKey(builder *request.Builder) (string, int, error) {
+	built, err := builder.Build()
 	if err != nil {
-		glog.V(10).Infof("Failed to send request to ADP API: %s", err)
+		return "", 0, err
+	}
 
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	cacheKey, err := built.CacheKey()
+	if err != nil {
+		return "", 0, err
 	}
 
-	cache.Add(cacheKey, response)
+	taxYear, err := built.TaxYear()
+	if err != nil {
+		return "", 0, err
+	}
 
-	return response, nil
+	return cacheKey, taxYear, nil
 }
 
 // HandleHealthCheck handles a health check request. It always returns a 204 No Content response.
@@ -0,0 +1,421 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tslnc04/tax-calculator/internal/fakeadp"
+	"github.com/tslnc04/tax-calculator/internal/jurisdiction"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// newTestOptions starts a fake ADP server and returns it along with options for taxcalcd that send requests to it and
+// look up jurisdictions in a registry loaded from it. The server is closed once the test is done.
+func newTestOptions(t *testing.T) (*fakeadp.Server, Options) {
+	t.Helper()
+
+	server := fakeadp.NewServer()
+	t.Cleanup(server.Close)
+
+	registry := jurisdiction.NewRegistry(&jurisdiction.Loader{BaseURL: server.URL})
+
+	err := registry.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load jurisdictions from fake server: %s", err)
+	}
+
+	return server, Options{CacheSize: 10, RateLimit: time.Millisecond, APIURL: server.APIURL(), Registry: registry}
+}
+
+// newTestHandler starts a fake ADP server and returns a mux for taxcalcd that sends requests to it, as described by
+// [newTestOptions].
+func newTestHandler(t *testing.T) (*fakeadp.Server, http.Handler) {
+	t.Helper()
+
+	server, options := newTestOptions(t)
+
+	mux, err := NewRequestMux(options)
+	if err != nil {
+		t.Fatalf("NewRequestMux() error = %v", err)
+	}
+
+	return server, mux
+}
+
+// serve sends a GET request for the target to the handler with the headers and returns the recorded response.
+func serve(handler http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestServeHTTPCSV(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, APIBasePath+"/?salary=120000&state=CA&pay-frequency=monthly")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != csvContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, csvContentType)
+	}
+
+	if body := recorder.Body.String(); body != "7435.00\n" {
+		t.Errorf("body = %q, want %q", body, "7435.00\n")
+	}
+}
+
+func TestServeHTTPSupplementalPay(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, APIBasePath+"/?salary=120000&state=CA&pay-frequency=monthly&supplemental=bonus=5000")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	if body := recorder.Body.String(); body != "10552.50\n" {
+		t.Errorf("body = %q, want %q", body, "10552.50\n")
+	}
+}
+
+func TestServeHTTPHourlyPay(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantGross float64
+	}{
+		{"periodic salary", "salary=5000&salary-frequency=periodic", 5000},
+		{"hourly only", "hourly-rate=30&hours=80", 2400},
+		{"hourly with overtime", "hourly-rate=30&hours=80&overtime-hours=10&doubletime-hours=2", 2970},
+		{"salary and hourly", "salary=12000&hourly-rate=30&hours=10", 1300},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(handler, APIBasePath+"/?format=json&"+test.query)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+			}
+
+			decoded := &response.Response{}
+
+			err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+			if err != nil {
+				t.Fatalf("failed to decode JSON response: %s", err)
+			}
+
+			if decoded.Gross.Amount != test.wantGross {
+				t.Errorf("gross = %.2f, want %.2f", decoded.Gross.Amount, test.wantGross)
+			}
+		})
+	}
+}
+
+func TestServeHTTPPayFrequency(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		payFrequency string
+		wantGross    float64
+	}{
+		{"daily", 500},
+		{"bi-weekly", 5000},
+		{"biweekly", 5000},
+		{"semi-monthly", 5416.67},
+		{"quarterly", 32500},
+		{"semi-annual", 65000},
+		{"annual", 130000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.payFrequency, func(t *testing.T) {
+			recorder := serve(handler, APIBasePath+"/?format=json&salary=130000&pay-frequency="+test.payFrequency)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+			}
+
+			decoded := &response.Response{}
+
+			err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+			if err != nil {
+				t.Fatalf("failed to decode JSON response: %s", err)
+			}
+
+			if decoded.Gross.Amount != test.wantGross {
+				t.Errorf("gross = %.2f, want %.2f", decoded.Gross.Amount, test.wantGross)
+			}
+		})
+	}
+}
+
+func TestServeHTTPJSON(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		name    string
+		target  string
+		headers []string
+	}{
+		{"format parameter", APIBasePath + "/?salary=120000&state=CA&format=json", nil},
+		{"accept header", APIBasePath + "/?salary=120000&state=CA", []string{"Accept", "application/json"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(handler, test.target, test.headers...)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+			}
+
+			decoded := &response.Response{}
+
+			err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+			if err != nil {
+				t.Fatalf("failed to decode JSON response: %s", err)
+			}
+
+			if decoded.Net.Amount != 7435 || decoded.Gross.Amount != 10000 {
+				t.Errorf("gross, net = %.2f, %.2f, want 10000.00, 7435.00", decoded.Gross.Amount, decoded.Net.Amount)
+			}
+
+			if len(decoded.Taxes.State.Entities) != 1 {
+				t.Errorf("state tax entities = %+v, want one", decoded.Taxes.State.Entities)
+			}
+		})
+	}
+}
+
+func TestServeHTTPErrors(t *testing.T) {
+	server, handler := newTestHandler(t)
+
+	tests := []struct {
+		name       string
+		target     string
+		failures   []int
+		wantStatus int
+	}{
+		{"missing salary", APIBasePath + "/?state=CA", nil, http.StatusBadRequest},
+		{"invalid salary", APIBasePath + "/?salary=lots", nil, http.StatusBadRequest},
+		{"invalid format", APIBasePath + "/?salary=1000&format=xml", nil, http.StatusBadRequest},
+		{"negative salary", APIBasePath + "/?salary=-1000", nil, http.StatusBadRequest},
+		{"invalid pay date", APIBasePath + "/?salary=1000&pay-date=03/15/2024", nil, http.StatusBadRequest},
+		{"old pay date", APIBasePath + "/?salary=1000&pay-date=2015-03-15", nil, http.StatusBadRequest},
+		{"hours without hourly rate", APIBasePath + "/?salary=1000&hours=40", nil, http.StatusBadRequest},
+		{"negative hourly rate", APIBasePath + "/?hourly-rate=-20&hours=40", nil, http.StatusBadRequest},
+		{"invalid salary frequency", APIBasePath + "/?salary=1000&salary-frequency=weekly", nil, http.StatusBadRequest},
+		{"invalid pay frequency", APIBasePath + "/?salary=1000&pay-frequency=fortnightly", nil, http.StatusBadRequest},
+		{"invalid supplemental", APIBasePath + "/?salary=1000&supplemental=raise=100", nil, http.StatusBadRequest},
+		{"unknown state", APIBasePath + "/?salary=1000&state=ZZ", nil, http.StatusNotFound},
+		{"upstream client error", APIBasePath + "/?salary=2000", []int{http.StatusBadRequest}, http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server.FailNext(test.failures...)
+
+			recorder := serve(handler, test.target)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestServeHTTPCachesResponses(t *testing.T) {
+	server, handler := newTestHandler(t)
+
+	for range 3 {
+		recorder := serve(handler, APIBasePath+"/?salary=60000&state=TX")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+		}
+	}
+
+	if calls := server.CalculationCount(); calls != 1 {
+		t.Errorf("server received %d calls, want 1", calls)
+	}
+}
+
+func TestServeHTTPCachesResponsesByJurisdictionRole(t *testing.T) {
+	server, handler := newTestHandler(t)
+
+	// The same state as where the employee lives, works, or both must not share a cached response.
+	for _, query := range []string{"state=NJ", "live-state=NJ", "work-state=NJ", "live-state=NJ&work-state=NY"} {
+		recorder := serve(handler, APIBasePath+"/?salary=60000&"+query)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status for %s = %d, want %d: %s", query, recorder.Code, http.StatusOK, recorder.Body)
+		}
+	}
+
+	if calls := server.CalculationCount(); calls != 4 {
+		t.Errorf("server received %d calls, want 4", calls)
+	}
+}
+
+func TestServeHTTPCachesResponsesByTaxYear(t *testing.T) {
+	server, handler := newTestHandler(t)
+
+	for _, payDate := range []string{"2023-03-15", "2023-12-31", "2024-01-01"} {
+		recorder := serve(handler, APIBasePath+"/?salary=60000&state=TX&pay-date="+payDate)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+		}
+	}
+
+	if calls := server.CalculationCount(); calls != 2 {
+		t.Errorf("server received %d calls, want 2", calls)
+	}
+}
+
+func TestServeHTTPDiskCacheSurvivesRestart(t *testing.T) {
+	server, options := newTestOptions(t)
+	options.CacheDir = t.TempDir()
+
+	for range 2 {
+		mux, err := NewRequestMux(options)
+		if err != nil {
+			t.Fatalf("NewRequestMux() error = %v", err)
+		}
+
+		recorder := serve(mux, APIBasePath+"/?salary=60000&state=TX")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+		}
+	}
+
+	if calls := server.CalculationCount(); calls != 1 {
+		t.Errorf("server received %d calls, want 1", calls)
+	}
+}
+
+func TestServeJurisdictions(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, APIBasePath+"/jurisdictions", "Accept", "application/json")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	if version := recorder.Header().Get(pccVersionHeader); version != fakeadp.PCCVersion {
+		t.Errorf("%s = %q, want %q", pccVersionHeader, version, fakeadp.PCCVersion)
+	}
+
+	decoded := &jurisdiction.Listing{}
+
+	err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+	if err != nil {
+		t.Fatalf("failed to decode JSON response: %s", err)
+	}
+
+	if decoded.PCCVersion != fakeadp.PCCVersion {
+		t.Errorf("PCCVersion = %q, want %q", decoded.PCCVersion, fakeadp.PCCVersion)
+	}
+
+	if want := 1 + len(fakeadp.States) + len(fakeadp.Locals); len(decoded.Jurisdictions) != want {
+		t.Errorf("got %d jurisdictions, want %d", len(decoded.Jurisdictions), want)
+	}
+
+	recorder = serve(handler, APIBasePath+"/jurisdictions")
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != csvContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, csvContentType)
+	}
+
+	if !strings.HasPrefix(recorder.Body.String(), "id,code,name,level,parent\n") {
+		t.Errorf("body = %q, want it to start with the CSV header", recorder.Body)
+	}
+}
+
+func TestServeHTTPLoadsJurisdictionsConcurrently(t *testing.T) {
+	server, options := newTestOptions(t)
+	options.Registry = jurisdiction.NewRegistry(&jurisdiction.Loader{BaseURL: server.URL})
+
+	mux, err := NewRequestMux(options)
+	if err != nil {
+		t.Fatalf("NewRequestMux() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, state := range []string{"CA", "NY", "TX", "WA", "CO"} {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			recorder := serve(mux, APIBasePath+"/?salary=60000&state="+state)
+			if recorder.Code != http.StatusOK {
+				t.Errorf("state %s status = %d, want %d: %s", state, recorder.Code, http.StatusOK, recorder.Body)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		target  string
+		accept  string
+		want    outputFormat
+		wantErr bool
+	}{
+		{target: "/", want: csvFormat},
+		{target: "/", accept: "application/json", want: jsonFormat},
+		{target: "/", accept: "text/csv;q=0.5, application/json;q=0.9", want: jsonFormat},
+		{target: "/", accept: "application/json;q=0.1, */*", want: csvFormat},
+		{target: "/", accept: "text/html", want: csvFormat},
+		{target: "/?format=json", accept: "text/csv", want: jsonFormat},
+		{target: "/?format=CSV", accept: "application/json", want: csvFormat},
+		{target: "/?format=xml", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.target+" "+test.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.target, nil)
+			req.Header.Set("Accept", test.accept)
+
+			got, err := negotiateFormat(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("negotiateFormat() error = %v, wantErr %t", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("negotiateFormat() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleHealthCheck(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, "/")
+
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/request"
+	"github.com/tslnc04/tax-calculator/internal/response"
+	"github.com/tslnc04/tax-calculator/internal/sweep"
+)
+
+// sweepResponse is the JSON response for a sweep. It contains a point for each salary in the range.
+type sweepResponse struct {
+	Points []sweep.Point `json:"points"`
+}
+
+// ServeSweep handles a request for calculating the taxes over a range of salaries. It expects the annual salaries to
+// start at the `from` query parameter and increase by `step` up to `to`, and accepts every other parameter of
+// [RequestHandler.ServeHTTP] except for salary. Since the salaries are annual, salary-frequency may not be periodic.
+// Each salary is calculated through the cache and rate limit like a single request, so a sweep of [sweep.MaxPoints]
+// uncached salaries takes [sweep.MaxPoints] times the rate limit. By default, it will return a CSV response with the
+// gross, net, total taxes, effective rate, and marginal rate for each salary. If JSON is requested, the same points are
+// returned as JSON.
+func (handler *RequestHandler) ServeSweep(resp http.ResponseWriter, req *http.Request) {
+	logRequest(req, "sweep")
+
+	format, err := negotiateFormat(req)
+	if err != nil {
+		glog.V(10).Infof("Failed to negotiate format: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to negotiate format: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	rng, params, err := parseSweepParams(req.URL.Query())
+	if err != nil {
+		glog.V(10).Infof("Failed to parse request params: %s", err)
+
+		http.Error(resp, fmt.Sprintf("failed to parse request params: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	points, err := sweep.Run(req.Context(), rng, func(ctx context.Context, salary float64) (*response.Response, error) {
+		step := *params
+		step.salary = salary
+
+		return handler.retrieveOrRequest(ctx, &step)
+	})
+	if err != nil {
+		glog.V(10).Infof("Failed to run sweep: %s", err)
+
+		writeError(resp, "failed to run sweep", err)
+
+		return
+	}
+
+	glog.V(10).Infof("Responding with %d points to sweep request", len(points))
+
+	if format == jsonFormat {
+		writeJSON(resp, http.StatusOK, sweepResponse{Points: points})
+
+		return
+	}
+
+	resp.Header().Set("Content-Type", csvContentType)
+	resp.WriteHeader(http.StatusOK)
+
+	err = sweep.WriteCSV(resp, points)
+	if err != nil {
+		glog.V(10).Infof("Failed to write CSV response: %s", err)
+	}
+}
+
+// parseSweepParams parses the range of annual salaries and every other request parameter for a sweep. Each of from,
+// to, and step must be specified, and salary-frequency may not be periodic.
+func parseSweepParams(query url.Values) (sweep.Range, *requestParams, error) {
+	var err error
+
+	rng := sweep.Range{}
+
+	rangeParams := []struct {
+		name  string
+		value *float64
+	}{
+		{"from", &rng.From},
+		{"to", &rng.To},
+		{"step", &rng.Step},
+	}
+
+	for _, rangeParam := range rangeParams {
+		if query.Get(rangeParam.name) == "" {
+			return rng, nil, fmt.Errorf("%s must be specified", rangeParam.name)
+		}
+
+		*rangeParam.value, err = parseFloatParam(query, rangeParam.name)
+		if err != nil {
+			return rng, nil, err
+		}
+	}
+
+	err = rng.Validate()
+	if err != nil {
+		return rng, nil, err
+	}
+
+	params, err := parseOptionParams(query)
+	if err != nil {
+		return rng, nil, err
+	}
+
+	if params.salaryFrequency == request.PeriodicSalaryFrequency {
+		return rng, nil, fmt.Errorf("salary-frequency must be annual for a sweep")
+	}
+
+	return rng, params, nil
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServeSweep(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	recorder := serve(handler, APIBasePath+"/sweep?from=60000&to=120000&step=30000&state=CA&pay-frequency=monthly")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4: %q", len(lines), lines)
+	}
+
+	if lines[3] != "120000.00,10000.00,7435.00,2565.00,0.2565,0.2565" {
+		t.Errorf("last row = %q, want %q", lines[3], "120000.00,10000.00,7435.00,2565.00,0.2565,0.2565")
+	}
+
+	recorder = serve(handler, APIBasePath+"/sweep?from=60000&to=120000&step=30000&state=CA&format=json")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	decoded := &sweepResponse{}
+
+	err := json.Unmarshal(recorder.Body.Bytes(), decoded)
+	if err != nil {
+		t.Fatalf("failed to decode JSON response: %s", err)
+	}
+
+	if len(decoded.Points) != 3 || decoded.Points[0].MarginalRate != nil {
+		t.Errorf("points = %+v, want three with no marginal rate for the first", decoded.Points)
+	}
+}
+
+func TestServeSweepErrors(t *testing.T) {
+	_, handler := newTestHandler(t)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"missing step", APIBasePath + "/sweep?from=1000&to=2000", http.StatusBadRequest},
+		{"invalid from", APIBasePath + "/sweep?from=lots&to=2000&step=100", http.StatusBadRequest},
+		{"reversed range", APIBasePath + "/sweep?from=2000&to=1000&step=100", http.StatusBadRequest},
+		{"too many points", APIBasePath + "/sweep?from=0&to=1000000&step=1", http.StatusBadRequest},
+		{"periodic salary", APIBasePath + "/sweep?from=1000&to=2000&step=1000&salary-frequency=periodic",
+			http.StatusBadRequest},
+		{"unknown state", APIBasePath + "/sweep?from=1000&to=2000&step=1000&state=ZZ", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(handler, test.target)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
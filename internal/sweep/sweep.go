@@ -0,0 +1,137 @@
+// Package sweep calculates the taxes for a range of salaries, along with the effective and marginal tax rates at each
+// salary. The results are meant for charting how taxes change with income.
+package sweep
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// MaxPoints is the maximum number of salaries in a single sweep. Each salary is a request to the ADP API, so it is kept
+// small enough that a sweep finishes in about a minute at the default rate limit of one request per second.
+const MaxPoints = 60
+
+// Range is an inclusive range of annual salaries, starting at From and increasing by Step until To is passed.
+type Range struct {
+	From float64
+	To   float64
+	Step float64
+}
+
+// Validate returns an error if the range is empty, does not increase, or has more than [MaxPoints] salaries.
+func (rng Range) Validate() error {
+	switch {
+	case rng.From < 0 || math.IsNaN(rng.From) || math.IsInf(rng.From, 0):
+		return fmt.Errorf("from must be a non-negative number")
+	case rng.To < rng.From || math.IsNaN(rng.To) || math.IsInf(rng.To, 0):
+		return fmt.Errorf("to must be a number greater than or equal to from")
+	case rng.Step <= 0 || math.IsNaN(rng.Step):
+		return fmt.Errorf("step must be a positive number")
+	case math.Floor((rng.To-rng.From)/rng.Step)+1 > MaxPoints:
+		return fmt.Errorf("range must have at most %d salaries", MaxPoints)
+	default:
+		return nil
+	}
+}
+
+// Salaries returns every salary in the range. The range must be valid.
+func (rng Range) Salaries() []float64 {
+	count := int(math.Floor((rng.To-rng.From)/rng.Step)) + 1
+	salaries := make([]float64, count)
+
+	for i := range salaries {
+		// Multiplying instead of repeatedly adding avoids accumulating floating point error.
+		salaries[i] = rng.From + float64(i)*rng.Step
+	}
+
+	return salaries
+}
+
+// Point is the result of the calculation for a single salary in a sweep. The gross, net, and taxes are per pay
+// period. The marginal rate is the rate of the additional taxes relative to the additional gross income since the
+// previous point, so it is nil for the first point.
+type Point struct {
+	Salary        float64  `json:"salary"`
+	Gross         float64  `json:"gross"`
+	Net           float64  `json:"net"`
+	TotalTaxes    float64  `json:"totalTaxes"`
+	EffectiveRate float64  `json:"effectiveRate"`
+	MarginalRate  *float64 `json:"marginalRate"`
+}
+
+// CalculateFunc calculates the response for an annual salary. It is called for each salary in a sweep.
+type CalculateFunc func(ctx context.Context, salary float64) (*response.Response, error)
+
+// Run calculates every salary in the range in order and returns a point for each. If any calculation fails, the sweep
+// is stopped and the error is returned.
+func Run(ctx context.Context, rng Range, calculate CalculateFunc) ([]Point, error) {
+	err := rng.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	salaries := rng.Salaries()
+	points := make([]Point, len(salaries))
+
+	for i, salary := range salaries {
+		glog.V(10).Infof("Calculating sweep point %d of %d for salary %.2f", i+1, len(salaries), salary)
+
+		resp, err := calculate(ctx, salary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate salary %.2f: %w", salary, err)
+		}
+
+		points[i] = Point{
+			Salary:     salary,
+			Gross:      resp.Gross.Amount,
+			Net:        resp.Net.Amount,
+			TotalTaxes: resp.Taxes.SummaryEntity.Amount,
+		}
+
+		if points[i].Gross != 0 {
+			points[i].EffectiveRate = points[i].TotalTaxes / points[i].Gross
+		}
+
+		if i > 0 && points[i].Gross != points[i-1].Gross {
+			marginalRate := (points[i].TotalTaxes - points[i-1].TotalTaxes) / (points[i].Gross - points[i-1].Gross)
+			points[i].MarginalRate = &marginalRate
+		}
+	}
+
+	return points, nil
+}
+
+// WriteCSV writes the points as CSV with a header row. Rates are written as fractions rather than percentages, and the
+// marginal rate is empty when it is not defined.
+func WriteCSV(writer io.Writer, points []Point) error {
+	csvWriter := csv.NewWriter(writer)
+
+	_ = csvWriter.Write([]string{"salary", "gross", "net", "total_taxes", "effective_rate", "marginal_rate"})
+
+	for _, point := range points {
+		marginalRate := ""
+		if point.MarginalRate != nil {
+			marginalRate = strconv.FormatFloat(*point.MarginalRate, 'f', 4, 64)
+		}
+
+		_ = csvWriter.Write([]string{
+			strconv.FormatFloat(point.Salary, 'f', 2, 64),
+			strconv.FormatFloat(point.Gross, 'f', 2, 64),
+			strconv.FormatFloat(point.Net, 'f', 2, 64),
+			strconv.FormatFloat(point.TotalTaxes, 'f', 2, 64),
+			strconv.FormatFloat(point.EffectiveRate, 'f', 4, 64),
+			marginalRate,
+		})
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
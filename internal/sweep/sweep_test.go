@@ -0,0 +1,125 @@
+package sweep
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tslnc04/tax-calculator/internal/response"
+)
+
+// bracketCalculate calculates a monthly response with no tax on the first 2000 of gross pay and 25% on the rest.
+func bracketCalculate(_ context.Context, salary float64) (*response.Response, error) {
+	gross := salary / 12
+	taxes := math.Max(0, gross-2000) * 0.25
+
+	resp := &response.Response{}
+	resp.Gross.Amount = gross
+	resp.Taxes.SummaryEntity.Amount = taxes
+	resp.Net.Amount = gross - taxes
+
+	return resp, nil
+}
+
+func TestRangeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rng     Range
+		wantErr bool
+	}{
+		{"valid", Range{From: 30000, To: 300000, Step: 5000}, false},
+		{"single point", Range{From: 30000, To: 30000, Step: 1}, false},
+		{"negative from", Range{From: -1, To: 10, Step: 1}, true},
+		{"to before from", Range{From: 10, To: 5, Step: 1}, true},
+		{"zero step", Range{From: 0, To: 10, Step: 0}, true},
+		{"NaN to", Range{From: 0, To: math.NaN(), Step: 1}, true},
+		{"too many points", Range{From: 0, To: MaxPoints, Step: 1}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.rng.Validate()
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRangeSalaries(t *testing.T) {
+	got := Range{From: 30000, To: 45000, Step: 4000}.Salaries()
+	want := []float64{30000, 34000, 38000, 42000}
+
+	if len(got) != len(want) {
+		t.Fatalf("Salaries() = %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Salaries()[%d] = %.2f, want %.2f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	points, err := Run(context.Background(), Range{From: 12000, To: 48000, Step: 12000}, bracketCalculate)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	wantEffective := []float64{0, 0, 0.0833, 0.125}
+	wantMarginal := []float64{math.NaN(), 0, 0.25, 0.25}
+
+	if len(points) != len(wantEffective) {
+		t.Fatalf("got %d points, want %d", len(points), len(wantEffective))
+	}
+
+	for i, point := range points {
+		if math.Abs(point.EffectiveRate-wantEffective[i]) > 0.0001 {
+			t.Errorf("point %d effective rate = %.4f, want %.4f", i, point.EffectiveRate, wantEffective[i])
+		}
+
+		if i == 0 {
+			if point.MarginalRate != nil {
+				t.Errorf("point 0 marginal rate = %.4f, want nil", *point.MarginalRate)
+			}
+
+			continue
+		}
+
+		if point.MarginalRate == nil || math.Abs(*point.MarginalRate-wantMarginal[i]) > 0.0001 {
+			t.Errorf("point %d marginal rate = %v, want %.4f", i, point.MarginalRate, wantMarginal[i])
+		}
+	}
+
+	var buf bytes.Buffer
+
+	err = WriteCSV(&buf, points)
+	if err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[1] != "12000.00,1000.00,1000.00,0.00,0.0000," {
+		t.Errorf("first CSV row = %q, want %q", lines[1], "12000.00,1000.00,1000.00,0.00,0.0000,")
+	}
+
+	if lines[4] != "48000.00,4000.00,3500.00,500.00,0.1250,0.2500" {
+		t.Errorf("last CSV row = %q, want %q", lines[4], "48000.00,4000.00,3500.00,500.00,0.1250,0.2500")
+	}
+}
+
+func TestRunError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	_, err := Run(context.Background(), Range{From: 0, To: 10, Step: 1},
+		func(context.Context, float64) (*response.Response, error) {
+			return nil, errFailed
+		})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Run() error = %v, want %v", err, errFailed)
+	}
+}